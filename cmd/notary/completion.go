@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/notary/trustmanager"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cmdCompletionTemplate = usageTemplate{
+	Use:   "completion",
+	Short: "Generates shell completion scripts.",
+	Long:  "Generates shell completion scripts for notary.  Only bash is currently supported.",
+}
+
+var cmdCompletionBashTemplate = usageTemplate{
+	Use:   "bash",
+	Short: "Generates a bash completion script.",
+	Long:  "Generates a bash completion script for notary and writes it to STDOUT.  Besides commands and flags, the script completes the IDs of private keys and the GUNs those keys belong to, which it looks up from the local trust directory when completing.",
+	Example: `  notary completion bash > /etc/bash_completion.d/notary
+  source <(notary completion bash)`,
+}
+
+var cmdCompletionKeyIDsTemplate = usageTemplate{
+	Use:   "key-ids",
+	Short: "Lists the IDs of all private keys in the trust directory, for use by shell completion.",
+	Long:  "Lists the IDs of all private keys in the trust directory, one per line, for use by shell completion.",
+}
+
+var cmdCompletionGUNsTemplate = usageTemplate{
+	Use:   "guns",
+	Short: "Lists the GUNs of all private keys in the trust directory, for use by shell completion.",
+	Long:  "Lists the GUNs of all private keys in the trust directory, one per line, for use by shell completion.",
+}
+
+// bashCompletionFunction is called by the cobra generated bash completion when
+// it has nothing else to complete, and completes key IDs or GUNs for commands
+// that take them as their first positional argument
+const bashCompletionFunction = `
+__notary_complete_key_ids()
+{
+    COMPREPLY=( $(compgen -W "$(notary completion key-ids 2>/dev/null)" -- "$cur") )
+}
+
+__notary_complete_guns()
+{
+    COMPREPLY=( $(compgen -W "$(notary completion guns 2>/dev/null)" -- "$cur") )
+}
+
+__custom_func()
+{
+    if [[ ${#nouns[@]} -ne 0 ]]; then
+        return
+    fi
+    case ${last_command} in
+        notary_key_remove | notary_key_passwd)
+            __notary_complete_key_ids
+            ;;
+        notary_key_rotate | notary_delegation_* | notary_init | notary_list | notary_add | notary_addhash | \
+        notary_remove | notary_lookup | notary_publish | notary_status | notary_verify | notary_witness | notary_delete)
+            __notary_complete_guns
+            ;;
+    esac
+}
+`
+
+type completionCommander struct {
+	// this needs to be set
+	configGetter func() (*viper.Viper, error)
+}
+
+func (c *completionCommander) GetCommand() *cobra.Command {
+	cmd := cmdCompletionTemplate.ToCommand(nil)
+	cmd.AddCommand(cmdCompletionBashTemplate.ToCommand(c.completionBash))
+
+	cmdKeyIDs := cmdCompletionKeyIDsTemplate.ToCommand(c.completionKeyIDs)
+	cmdKeyIDs.Hidden = true
+	cmd.AddCommand(cmdKeyIDs)
+
+	cmdGUNs := cmdCompletionGUNsTemplate.ToCommand(c.completionGUNs)
+	cmdGUNs.Hidden = true
+	cmd.AddCommand(cmdGUNs)
+	return cmd
+}
+
+func (c *completionCommander) completionBash(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		cmd.Usage()
+		return fmt.Errorf("bash completion does not take any positional arguments")
+	}
+	root := cmd.Root()
+	root.BashCompletionFunction = bashCompletionFunction
+	return root.GenBashCompletion(cmd.Out())
+}
+
+func (c *completionCommander) completionKeyIDs(cmd *cobra.Command, args []string) error {
+	keyInfos, err := c.listKeys()
+	if err != nil {
+		return err
+	}
+	keyIDs := make([]string, 0, len(keyInfos))
+	for keyID := range keyInfos {
+		keyIDs = append(keyIDs, filepath.Base(keyID))
+	}
+	sort.Strings(keyIDs)
+	for _, keyID := range keyIDs {
+		cmd.Println(keyID)
+	}
+	return nil
+}
+
+func (c *completionCommander) completionGUNs(cmd *cobra.Command, args []string) error {
+	keyInfos, err := c.listKeys()
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	var guns []string
+	for _, keyInfo := range keyInfos {
+		if keyInfo.Gun != "" && !seen[keyInfo.Gun] {
+			seen[keyInfo.Gun] = true
+			guns = append(guns, keyInfo.Gun)
+		}
+	}
+	sort.Strings(guns)
+	for _, gun := range guns {
+		cmd.Println(gun)
+	}
+	return nil
+}
+
+// listKeys returns the key info for all the private keys on disk.  Keys are
+// not decrypted, so no passphrase retriever is needed.
+func (c *completionCommander) listKeys() (map[string]trustmanager.KeyInfo, error) {
+	config, err := c.configGetter()
+	if err != nil {
+		return nil, err
+	}
+	directory := config.GetString("trust_dir")
+	fileKeyStore, err := trustmanager.NewKeyFileStore(directory, nil)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Failed to create private key store in directory: %s", directory)
+	}
+	return fileKeyStore.ListKeys(), nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/notary/trustmanager"
+	"github.com/docker/notary/tuf/data"
+	"github.com/docker/notary/tuf/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletionBash(t *testing.T) {
+	tempDir := tempDirWithConfig(t, "{}")
+	defer os.RemoveAll(tempDir)
+	configFile := filepath.Join(tempDir, "config.json")
+
+	b := new(bytes.Buffer)
+	cmd := NewNotaryCommand()
+	cmd.SetOutput(b)
+	cmd.SetArgs([]string{"-c", configFile, "completion", "bash"})
+	require.NoError(t, cmd.Execute())
+
+	script := b.String()
+	require.Contains(t, script, "__start_notary")
+	require.Contains(t, script, "__custom_func")
+	require.Contains(t, script, "_notary_key_remove")
+	// hidden helper commands are not offered as completions
+	require.NotContains(t, script, `commands+=("key-ids")`)
+}
+
+func TestCompletionKeyIDsAndGUNs(t *testing.T) {
+	tempDir := tempDirWithConfig(t, "{}")
+	defer os.RemoveAll(tempDir)
+	configFile := filepath.Join(tempDir, "config.json")
+
+	fileKeyStore, err := trustmanager.NewKeyFileStore(tempDir, ret)
+	require.NoError(t, err)
+	var keyIDs []string
+	for _, keyInfo := range []trustmanager.KeyInfo{
+		{Role: data.CanonicalRootRole},
+		{Role: data.CanonicalTargetsRole, Gun: "docker.com/notary"},
+		{Role: data.CanonicalSnapshotRole, Gun: "docker.com/notary"},
+		{Role: data.CanonicalTargetsRole, Gun: "docker.com/alpine"},
+	} {
+		key, err := utils.GenerateECDSAKey(rand.Reader)
+		require.NoError(t, err)
+		require.NoError(t, fileKeyStore.AddKey(keyInfo, key))
+		keyIDs = append(keyIDs, key.ID())
+	}
+
+	b := new(bytes.Buffer)
+	cmd := NewNotaryCommand()
+	cmd.SetOutput(b)
+	cmd.SetArgs([]string{"-c", configFile, "-d", tempDir, "completion", "key-ids"})
+	require.NoError(t, cmd.Execute())
+	listed := bytes.Fields(b.Bytes())
+	require.Len(t, listed, len(keyIDs))
+	for _, keyID := range keyIDs {
+		require.Contains(t, b.String(), keyID)
+	}
+
+	b.Reset()
+	cmd = NewNotaryCommand()
+	cmd.SetOutput(b)
+	cmd.SetArgs([]string{"-c", configFile, "-d", tempDir, "completion", "guns"})
+	require.NoError(t, cmd.Execute())
+	require.Equal(t, "docker.com/alpine\ndocker.com/notary\n", b.String())
+}
@@ -2,8 +2,8 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
-	"os"
 	"strings"
 
 	"github.com/docker/notary"
@@ -21,27 +21,34 @@ var cmdDelegationTemplate = usageTemplate{
 }
 
 var cmdDelegationListTemplate = usageTemplate{
-	Use:   "list [ GUN ]",
-	Short: "Lists delegations for the Global Unique Name.",
-	Long:  "Lists all delegations known to notary for a specific Global Unique Name.",
+	Use:     "list [ GUN ]",
+	Short:   "Lists delegations for the Global Unique Name.",
+	Long:    "Lists all delegations known to notary for a specific Global Unique Name.",
+	Example: `  notary delegation list docker.io/library/alpine`,
 }
 
 var cmdDelegationRemoveTemplate = usageTemplate{
 	Use:   "remove [ GUN ] [ Role ] <KeyID 1> ...",
 	Short: "Remove KeyID(s) from the specified Role delegation.",
 	Long:  "Remove KeyID(s) from the specified Role delegation in a specific Global Unique Name.",
+	Example: `  notary delegation remove docker.io/library/alpine targets/releases 0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+  notary delegation remove docker.io/library/alpine targets/releases --paths stable/
+  notary delegation remove docker.io/library/alpine targets/releases --yes`,
 }
 
 var cmdDelegationPurgeKeysTemplate = usageTemplate{
-	Use:   "purge [ GUN ]",
-	Short: "Remove KeyID(s) from all delegation roles in the given GUN.",
-	Long:  "Remove KeyID(s) from all delegation roles in the given GUN, for which the signing keys are available. Warnings will be printed for delegations that cannot be updated.",
+	Use:     "purge [ GUN ]",
+	Short:   "Remove KeyID(s) from all delegation roles in the given GUN.",
+	Long:    "Remove KeyID(s) from all delegation roles in the given GUN, for which the signing keys are available. Warnings will be printed for delegations that cannot be updated.",
+	Example: `  notary delegation purge docker.io/library/alpine --key 0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef`,
 }
 
 var cmdDelegationAddTemplate = usageTemplate{
 	Use:   "add [ GUN ] [ Role ] <X509 file path 1> ...",
 	Short: "Add a keys to delegation using the provided public key X509 certificates.",
 	Long:  "Add a keys to delegation using the provided public key PEM encoded X509 certificates in a specific Global Unique Name.",
+	Example: `  notary delegation add docker.io/library/alpine targets/releases releases.crt --all-paths
+  notary delegation add docker.io/library/alpine targets/releases releases.crt --paths stable/ --publish`,
 }
 
 type delegationCommander struct {
@@ -49,9 +56,12 @@ type delegationCommander struct {
 	configGetter func() (*viper.Viper, error)
 	retriever    notary.PassRetriever
 
-	paths                         []string
-	allPaths, removeAll, forceYes bool
-	keyIDs                        []string
+	paths               []string
+	allPaths, removeAll bool
+	keyIDs              []string
+
+	input      io.Reader
+	requireTTY bool
 
 	autoPublish bool
 }
@@ -66,9 +76,10 @@ func (d *delegationCommander) GetCommand() *cobra.Command {
 
 	cmdRemDelg := cmdDelegationRemoveTemplate.ToCommand(d.delegationRemove)
 	cmdRemDelg.Flags().StringSliceVar(&d.paths, "paths", nil, "List of paths to remove")
-	cmdRemDelg.Flags().BoolVarP(&d.forceYes, "yes", "y", false, "Answer yes to the removal question (no confirmation)")
 	cmdRemDelg.Flags().BoolVar(&d.allPaths, "all-paths", false, "Remove all paths from this delegation")
 	cmdRemDelg.Flags().BoolVarP(&d.autoPublish, "publish", "p", false, htAutoPublish)
+	cmdRemDelg.Flags().BoolVar(&d.requireTTY, "require-tty", false,
+		"Refuse to read the confirmation for removing a whole delegation unless STDIN is a terminal")
 	cmd.AddCommand(cmdRemDelg)
 
 	cmdAddDelg := cmdDelegationAddTemplate.ToCommand(d.delegationAdd)
@@ -91,6 +102,9 @@ func (d *delegationCommander) delegationPurgeKeys(cmd *cobra.Command, args []str
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 
 	config, err := d.configGetter()
 	if err != nil {
@@ -140,6 +154,9 @@ func (d *delegationCommander) delegationsList(cmd *cobra.Command, args []string)
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 
 	rt, err := getTransport(config, gun, readOnly)
 	if err != nil {
@@ -182,6 +199,9 @@ func (d *delegationCommander) delegationRemove(cmd *cobra.Command, args []string
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 	role := args[1]
 
 	// Check if role is valid delegation name before requiring any user input
@@ -219,10 +239,13 @@ func (d *delegationCommander) delegationRemove(cmd *cobra.Command, args []string
 	}
 
 	if d.removeAll {
+		if err := checkConfirmationInput(cmd, d.input, d.requireTTY); err != nil {
+			return err
+		}
 		cmd.Println("\nAre you sure you want to remove all data for this delegation? (yes/no)")
 		// Ask for confirmation before force removing delegation
-		if !d.forceYes {
-			confirmed := askConfirm(os.Stdin)
+		if !assumeYes(cmd) {
+			confirmed := askConfirm(d.input)
 			if !confirmed {
 				fatalf("Aborting action.")
 			}
@@ -286,6 +309,9 @@ func (d *delegationCommander) delegationAdd(cmd *cobra.Command, args []string) e
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 	role := args[1]
 
 	pubKeys := []data.PublicKey{}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/x509"
 	"io/ioutil"
@@ -133,6 +134,22 @@ func TestRemoveAllInvalidDelegationName(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestRemoveAllRequireTTYRefusesNonTerminalInput(t *testing.T) {
+	// Setup commander
+	tmpDir, err := ioutil.TempDir("/tmp", "notary-cmd-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	commander := setup(tmpDir)
+	cmd := commander.GetCommand()
+	commander.input = bytes.NewBuffer([]byte("yes\n"))
+	commander.requireTTY = true
+
+	// Should error because the confirmation would be read from piped input
+	err = commander.delegationRemove(cmd, []string{"gun", "targets/delegation"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "STDIN is not a terminal")
+}
+
 func TestAddInvalidNumArgs(t *testing.T) {
 	// Setup commander
 	tmpDir, err := ioutil.TempDir("/tmp", "notary-cmd-test-")
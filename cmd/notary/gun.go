@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// validateGUN checks that the given GUN can be used to name a repository.  A
+// GUN is one or more non-empty segments separated by "/", as registries use
+// for image names, e.g. "docker.io/library/alpine" or
+// "myregistry.example.com:5000/team/app".  The returned error says why the GUN
+// was rejected.
+func validateGUN(gun string) error {
+	if gun == "" {
+		return invalidGUN(gun, "it is empty")
+	}
+	if !utf8.ValidString(gun) {
+		return invalidGUN(gun, "it is not valid UTF-8")
+	}
+	for i, r := range gun {
+		switch {
+		case unicode.IsSpace(r):
+			return invalidGUN(gun, fmt.Sprintf("it contains whitespace (%q at offset %d)", r, i))
+		case !unicode.IsPrint(r):
+			return invalidGUN(gun, fmt.Sprintf("it contains a non-printable character (%q at offset %d)", r, i))
+		case r == '\\':
+			return invalidGUN(gun, fmt.Sprintf("it contains a backslash (at offset %d), segments must be separated by \"/\"", i))
+		}
+	}
+	if strings.HasPrefix(gun, "/") {
+		return invalidGUN(gun, "it begins with a slash")
+	}
+	if strings.HasSuffix(gun, "/") {
+		return invalidGUN(gun, "it ends with a slash")
+	}
+	for _, segment := range strings.Split(gun, "/") {
+		switch segment {
+		case "":
+			return invalidGUN(gun, "it contains an empty segment (\"//\")")
+		case ".", "..":
+			return invalidGUN(gun, fmt.Sprintf("it contains a %q segment", segment))
+		}
+	}
+	return nil
+}
+
+func invalidGUN(gun, reason string) error {
+	return fmt.Errorf("invalid GUN %q: %s", gun, reason)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGUNAccepts(t *testing.T) {
+	for _, gun := range []string{
+		"gun",
+		"docker.io/library/alpine",
+		"myregistry.example.com:5000/team/app",
+		"localhost:5000/a/b/c/d",
+		"registry.example.com/team/app-name_2.0",
+	} {
+		require.NoError(t, validateGUN(gun), gun)
+	}
+}
+
+func TestValidateGUNRejects(t *testing.T) {
+	for gun, reason := range map[string]string{
+		"":                          "it is empty",
+		"docker.io/library/alpine/": "it ends with a slash",
+		"/docker.io/library/alpine": "it begins with a slash",
+		`\docker.io\library`:        "it contains a backslash (at offset 0)",
+		"docker.io//alpine":         `it contains an empty segment ("//")`,
+		"docker.io/../alpine":       `it contains a ".." segment`,
+		"docker.io/./alpine":        `it contains a "." segment`,
+		"docker.io/library/ alpine": `it contains whitespace (' ' at offset 18)`,
+		"docker.io/library\talpine": `it contains whitespace ('\t' at offset 17)`,
+		"docker.io/library\nalpine": `it contains whitespace ('\n' at offset 17)`,
+		"docker.io/lib\x00rary":     `it contains a non-printable character ('\x00' at offset 13)`,
+		"docker.io/lib\x1brary":     `it contains a non-printable character ('\x1b' at offset 13)`,
+		"docker.io/lib\xffrary":     "it is not valid UTF-8",
+	} {
+		err := validateGUN(gun)
+		require.Error(t, err, "%q", gun)
+		require.Contains(t, err.Error(), reason, "%q", gun)
+	}
+}
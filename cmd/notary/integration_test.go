@@ -253,6 +253,161 @@ func TestClientTUFInteraction(t *testing.T) {
 	require.False(t, strings.Contains(string(output), target))
 }
 
+// Verifying a target writes the payload out only if it matches the trusted hashes
+func TestClientVerifyTarget(t *testing.T) {
+	// -- setup --
+	setUp(t)
+
+	tempDir := tempDirWithConfig(t, "{}")
+	defer os.RemoveAll(tempDir)
+
+	server := setupServer()
+	defer server.Close()
+
+	target := "sdgkadga"
+	content := bytes.Repeat([]byte("notary verify content\n"), 10000)
+	targetFile := filepath.Join(tempDir, "targetfile")
+	require.NoError(t, ioutil.WriteFile(targetFile, content, 0644))
+	tamperedFile := filepath.Join(tempDir, "tamperedfile")
+	require.NoError(t, ioutil.WriteFile(tamperedFile, append(content, 'x'), 0644))
+
+	// -- tests --
+	_, err := runCommand(t, tempDir, "-s", server.URL, "init", "gun")
+	require.NoError(t, err)
+	_, err = runCommand(t, tempDir, "add", "gun", target, targetFile)
+	require.NoError(t, err)
+	_, err = runCommand(t, tempDir, "-s", server.URL, "publish", "gun")
+	require.NoError(t, err)
+
+	// verify the original content - it is written to the output file
+	outFile := filepath.Join(tempDir, "verified")
+	_, err = runCommand(t, tempDir, "-s", server.URL, "verify", "gun", target, "-i", targetFile, "-o", outFile)
+	require.NoError(t, err)
+	verified, err := ioutil.ReadFile(outFile)
+	require.NoError(t, err)
+	require.Equal(t, content, verified)
+	// the output file is created subject to the umask, like the input file
+	outInfo, err := os.Stat(outFile)
+	require.NoError(t, err)
+	targetInfo, err := os.Stat(targetFile)
+	require.NoError(t, err)
+	require.Equal(t, targetInfo.Mode(), outInfo.Mode())
+
+	// verify tampered content - nothing is written to the output file
+	tamperedOutFile := filepath.Join(tempDir, "tampered-verified")
+	_, err = runCommand(t, tempDir, "-s", server.URL, "verify", "gun", target, "-i", tamperedFile, "-o", tamperedOutFile)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "data not present in the trusted collection")
+	require.Contains(t, err.Error(), "checksum for "+target+" did not match")
+	_, err = os.Stat(tamperedOutFile)
+	require.True(t, os.IsNotExist(err))
+
+	// the payload was spooled next to the output files, and the temporary
+	// files have been renamed into place or removed
+	spooled, err := filepath.Glob(filepath.Join(tempDir, ".*.tmp*"))
+	require.NoError(t, err)
+	require.Empty(t, spooled)
+
+	// verify quietly - nothing is written to the output file
+	quietOutFile := filepath.Join(tempDir, "quiet-verified")
+	_, err = runCommand(t, tempDir, "-s", server.URL, "verify", "gun", target, "-i", targetFile, "-o", quietOutFile, "-q")
+	require.NoError(t, err)
+	_, err = os.Stat(quietOutFile)
+	require.True(t, os.IsNotExist(err))
+}
+
+// Without --output, the verified payload is spooled to a temporary file and
+// then written to STDOUT, and the temporary file is removed afterwards.
+// Nothing is written to STDOUT if the payload does not verify.
+func TestClientVerifyTargetToStdout(t *testing.T) {
+	// -- setup --
+	setUp(t)
+
+	tempDir := tempDirWithConfig(t, "{}")
+	defer os.RemoveAll(tempDir)
+
+	server := setupServer()
+	defer server.Close()
+
+	target := "sdgkadga"
+	content := bytes.Repeat([]byte("notary verify content\n"), 10000)
+	targetFile := filepath.Join(tempDir, "targetfile")
+	require.NoError(t, ioutil.WriteFile(targetFile, content, 0644))
+	tamperedFile := filepath.Join(tempDir, "tamperedfile")
+	require.NoError(t, ioutil.WriteFile(tamperedFile, append(content, 'x'), 0644))
+
+	_, err := runCommand(t, tempDir, "-s", server.URL, "init", "gun")
+	require.NoError(t, err)
+	_, err = runCommand(t, tempDir, "add", "gun", target, targetFile)
+	require.NoError(t, err)
+	_, err = runCommand(t, tempDir, "-s", server.URL, "publish", "gun")
+	require.NoError(t, err)
+
+	// spool into a directory of our own so that we can check it is cleaned up
+	spoolDir := filepath.Join(tempDir, "spool")
+	require.NoError(t, os.Mkdir(spoolDir, 0700))
+	oldTmpDir := os.Getenv("TMPDIR")
+	require.NoError(t, os.Setenv("TMPDIR", spoolDir))
+	defer os.Setenv("TMPDIR", oldTmpDir)
+
+	verifyToStdout := func(input string) ([]byte, error) {
+		stdout, err := os.Create(filepath.Join(tempDir, "stdout"))
+		require.NoError(t, err)
+		defer stdout.Close()
+
+		oldStdout := os.Stdout
+		os.Stdout = stdout
+		_, verifyErr := runCommand(t, tempDir, "-s", server.URL, "verify", "gun", target, "-i", input)
+		os.Stdout = oldStdout
+
+		written, err := ioutil.ReadFile(stdout.Name())
+		require.NoError(t, err)
+		return written, verifyErr
+	}
+
+	// -- tests --
+	// the original content is written to STDOUT
+	written, err := verifyToStdout(targetFile)
+	require.NoError(t, err)
+	require.Equal(t, content, written)
+
+	// the tampered content is not written to STDOUT
+	written, err = verifyToStdout(tamperedFile)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum for "+target+" did not match")
+	require.Empty(t, written)
+
+	// the temporary files have been removed
+	spooled, err := ioutil.ReadDir(spoolDir)
+	require.NoError(t, err)
+	require.Empty(t, spooled)
+}
+
+// Commands that take a GUN reject an invalid one before doing anything with it
+func TestClientRejectsInvalidGUN(t *testing.T) {
+	setUp(t)
+
+	tempDir := tempDirWithConfig(t, "{}")
+	defer os.RemoveAll(tempDir)
+
+	for _, args := range [][]string{
+		{"init", "docker.io/library/"},
+		{"list", "docker.io//alpine"},
+		{"status", "docker.io/library/ alpine"},
+		{"delegation", "list", ""},
+		{"key", "rotate", "/docker.io/library/alpine", data.CanonicalSnapshotRole},
+		{"key", "export", "--gun", "docker.io/library/alpine/"},
+	} {
+		_, err := runCommand(t, tempDir, args...)
+		require.Error(t, err, "%v", args)
+		require.Contains(t, err.Error(), "invalid GUN", "%v", args)
+	}
+
+	// nothing was created for any of the GUNs
+	_, err := os.Stat(filepath.Join(tempDir, "tuf"))
+	require.True(t, os.IsNotExist(err))
+}
+
 func TestClientDeleteTUFInteraction(t *testing.T) {
 	// -- setup --
 	setUp(t)
@@ -1308,6 +1463,26 @@ func TestDefaultRootKeyGeneration(t *testing.T) {
 	assertNumKeys(t, tempDir, 1, 0, true)
 }
 
+// Tests that --yes removes a key without reading a confirmation, even when
+// --require-tty is passed and STDIN is not a terminal
+func TestKeyRemoveAssumeYes(t *testing.T) {
+	// -- setup --
+	setUp(t)
+
+	tempDir := tempDirWithConfig(t, "{}")
+	defer os.RemoveAll(tempDir)
+
+	// -- tests --
+	_, err := runCommand(t, tempDir, "key", "generate")
+	require.NoError(t, err)
+	root, _ := assertNumKeys(t, tempDir, 1, 0, true)
+
+	output, err := runCommand(t, tempDir, "--yes", "key", "remove", "--require-tty", root[0])
+	require.NoError(t, err)
+	require.Contains(t, output, "Deleted")
+	assertNumKeys(t, tempDir, 0, 0, true)
+}
+
 // Tests the interaction with the verbose and log-level flags
 func TestLogLevelFlags(t *testing.T) {
 	// Test default to fatal
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io"
 	"path/filepath"
@@ -30,42 +35,56 @@ var cmdKeyListTemplate = usageTemplate{
 	Use:   "list",
 	Short: "Lists keys.",
 	Long:  "Lists all keys known to notary.",
+	Example: `  notary key list
+  notary key list --count
+  notary key list --output json
+  notary key list --output-file /var/log/notary/keys.txt`,
 }
 
 var cmdRotateKeyTemplate = usageTemplate{
 	Use:   "rotate [ GUN ] [ key role ]",
 	Short: "Rotate a signing (non-root) key of the given type for the given Globally Unique Name and role.",
 	Long:  `Generates a new key for the given Globally Unique Name and role (one of "snapshot", "targets", "root", or "timestamp").  If rotating to a server-managed key, a new key is requested from the server rather than generated.  If the generation or key request is successful, the key rotation is immediately published.  No other changes, even if they are staged, will be published.`,
+	Example: `  notary key rotate docker.io/library/alpine snapshot --server-managed
+  notary key rotate docker.io/library/alpine targets`,
 }
 
 var cmdKeyGenerateRootKeyTemplate = usageTemplate{
 	Use:   "generate [ algorithm ]",
 	Short: "Generates a new root key with a given algorithm.",
 	Long:  "Generates a new root key with a given algorithm. If hardware key storage (e.g. a Yubikey) is available, the key will be stored both on hardware and on disk (so that it can be backed up).  Please make sure to back up and then remove this on-key disk immediately afterwards.",
+	Example: `  notary key generate
+  notary key generate rsa --backup-dir /mnt/backup`,
 }
 
 var cmdKeyRemoveTemplate = usageTemplate{
-	Use:   "remove [ keyID ]",
-	Short: "Removes the key with the given keyID.",
-	Long:  "Removes the key with the given keyID.  If the key is stored in more than one location, you will be asked which one to remove.",
+	Use:     "remove [ keyID ]",
+	Short:   "Removes the key with the given keyID.",
+	Long:    "Removes the key with the given keyID.  If the key is stored in more than one location, you will be asked which one to remove.",
+	Example: `  notary key remove 0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef`,
 }
 
 var cmdKeyPasswdTemplate = usageTemplate{
-	Use:   "passwd [ keyID ]",
-	Short: "Changes the passphrase for the key with the given keyID.",
-	Long:  "Changes the passphrase for the key with the given keyID.  Will require validation of the old passphrase.",
+	Use:     "passwd [ keyID ]",
+	Short:   "Changes the passphrase for the key with the given keyID.",
+	Long:    "Changes the passphrase for the key with the given keyID.  Will require validation of the old passphrase.",
+	Example: `  notary key passwd 0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef`,
 }
 
 var cmdKeyImportTemplate = usageTemplate{
 	Use:   "import pemfile [ pemfile ... ]",
 	Short: "Imports all keys from all provided .pem files",
 	Long:  "Imports all keys from all provided .pem files by reading each PEM block from the file and writing that block to a unique object in the local keystore. A Yubikey will be the prefferred import location for root keys if present.",
+	Example: `  notary key import backup.pem
+  notary key import delegation.key --role targets/releases --gun docker.io/library/alpine`,
 }
 
 var cmdKeyExportTemplate = usageTemplate{
 	Use:   "export",
 	Short: "Exports all keys from all local keystores. Can be filtered using the --key and --gun flags.",
 	Long:  "Exports all keys from all local keystores. Which keys are exported can be restricted by using the --key or --gun flags. By default the result is sent to stdout, it can be directed to a file with the -o flag. Keys stored in a Yubikey cannot be exported.",
+	Example: `  notary key export -o backup.pem
+  notary key export --gun docker.io/library/alpine -o alpine.pem`,
 }
 
 type keyCommander struct {
@@ -77,7 +96,15 @@ type keyCommander struct {
 	rotateKeyRole          string
 	rotateKeyServerManaged bool
 
-	input io.Reader
+	input      io.Reader
+	requireTTY bool
+
+	generateBackupDir string
+	generateEmitJSON  bool
+
+	listOutputFile   string
+	listOutputFormat string
+	listCount        bool
 
 	keysImportRole string
 	keysImportGUN  string
@@ -88,15 +115,33 @@ type keyCommander struct {
 
 func (k *keyCommander) GetCommand() *cobra.Command {
 	cmd := cmdKeyTemplate.ToCommand(nil)
-	cmd.AddCommand(cmdKeyListTemplate.ToCommand(k.keysList))
-	cmd.AddCommand(cmdKeyGenerateRootKeyTemplate.ToCommand(k.keysGenerateRootKey))
-	cmd.AddCommand(cmdKeyRemoveTemplate.ToCommand(k.keyRemove))
+	cmdKeysList := cmdKeyListTemplate.ToCommand(k.keysList)
+	cmdKeysList.Flags().StringVar(&k.listOutputFile, "output-file", "",
+		"Write the key listing to a file, instead of STDOUT")
+	cmdKeysList.Flags().BoolVar(&k.listCount, "count", false,
+		"Only print the number of keys for each role, instead of listing every key")
+	cmdKeysList.Flags().StringVar(&k.listOutputFormat, "output", "table",
+		"Format to list the keys in, one of: table, json (use --output-file to write to a file)")
+	cmd.AddCommand(cmdKeysList)
+	cmdGenerateRootKey := cmdKeyGenerateRootKeyTemplate.ToCommand(k.keysGenerateRootKey)
+	cmdGenerateRootKey.Flags().StringVar(&k.generateBackupDir, "backup-dir", "",
+		"Directory to also write an encrypted backup of the new root key to")
+	cmdGenerateRootKey.Flags().BoolVar(&k.generateEmitJSON, "emit-json", false,
+		"Describe the generated key as a JSON object on STDOUT, instead of the human readable output")
+	cmdGenerateRootKey.ValidArgs = []string{data.ECDSAKey, data.RSAKey}
+	cmd.AddCommand(cmdGenerateRootKey)
+	cmdRemoveKey := cmdKeyRemoveTemplate.ToCommand(k.keyRemove)
+	cmdRemoveKey.Flags().BoolVar(&k.requireTTY, "require-tty", false,
+		"Refuse to read the confirmation unless STDIN is a terminal")
+	cmd.AddCommand(cmdRemoveKey)
 	cmd.AddCommand(cmdKeyPasswdTemplate.ToCommand(k.keyPassphraseChange))
 	cmdRotateKey := cmdRotateKeyTemplate.ToCommand(k.keysRotate)
 	cmdRotateKey.Flags().BoolVarP(&k.rotateKeyServerManaged, "server-managed", "r",
 		false, "Signing and key management will be handled by the remote server "+
 			"(no key will be generated or stored locally). "+
 			"Required for timestamp role, optional for snapshot role")
+	cmdRotateKey.Flags().BoolVar(&k.requireTTY, "require-tty", false,
+		"Refuse to read the confirmation for a root key rotation unless STDIN is a terminal")
 	cmd.AddCommand(cmdRotateKey)
 
 	cmdKeysImport := cmdKeyImportTemplate.ToCommand(k.importKeys)
@@ -118,6 +163,8 @@ func (k *keyCommander) GetCommand() *cobra.Command {
 		nil,
 		"Key IDs to export",
 	)
+	cmdExport.MarkFlagCustom("gun", "__notary_complete_guns")
+	cmdExport.MarkFlagCustom("key", "__notary_complete_key_ids")
 	cmdExport.Flags().StringVarP(
 		&k.outFile,
 		"output",
@@ -135,17 +182,47 @@ func (k *keyCommander) keysList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("")
 	}
 
+	switch k.listOutputFormat {
+	case "", "table", "json":
+	default:
+		return fmt.Errorf("invalid output format %q, possible values are: table, json", k.listOutputFormat)
+	}
+
 	config, err := k.configGetter()
 	if err != nil {
 		return err
 	}
-	ks, err := k.getKeyStores(config, true, false)
+	ks, err := k.getKeyStores(config, k.getRetriever(), true, false)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case k.listOutputFormat == "json" && k.listCount:
+		err = jsonPrintKeyCounts(ks, &buf)
+	case k.listOutputFormat == "json":
+		err = jsonPrintKeys(ks, &buf)
+	case k.listCount:
+		prettyPrintKeyCounts(ks, &buf)
+	default:
+		prettyPrintKeys(ks, &buf)
+	}
 	if err != nil {
 		return err
 	}
 
+	if k.listOutputFile != "" {
+		return writeOutputFile(k.listOutputFile, buf.Bytes())
+	}
+
+	if k.listOutputFormat == "json" {
+		// nothing but the JSON is printed, so that it can be parsed
+		_, err = cmd.Out().Write(buf.Bytes())
+		return err
+	}
 	cmd.Println("")
-	prettyPrintKeys(ks, cmd.Out())
+	cmd.Print(buf.String())
 	cmd.Println("")
 	return nil
 }
@@ -159,28 +236,30 @@ func (k *keyCommander) keysGenerateRootKey(cmd *cobra.Command, args []string) er
 			"Please provide only one Algorithm as an argument to generate (rsa, ecdsa)")
 	}
 
-	// If no param is given to generate, generates an ecdsa key by default
-	algorithm := data.ECDSAKey
+	config, err := k.configGetter()
+	if err != nil {
+		return err
+	}
+
+	// If no param is given to generate, generates a key with the configured
+	// algorithm, which is ecdsa by default
+	algorithm, err := getRootKeyAlgorithm(config)
+	if err != nil {
+		return err
+	}
 
 	// If we were provided an argument lets attempt to use it as an algorithm
 	if len(args) > 0 {
 		algorithm = args[0]
 	}
 
-	allowedCiphers := map[string]bool{
-		data.ECDSAKey: true,
-		data.RSAKey:   true,
-	}
-
-	if !allowedCiphers[strings.ToLower(algorithm)] {
+	if !allowedRootKeyAlgorithms[strings.ToLower(algorithm)] {
 		return fmt.Errorf("Algorithm not allowed, possible values are: RSA, ECDSA")
 	}
-
-	config, err := k.configGetter()
-	if err != nil {
-		return err
-	}
-	ks, err := k.getKeyStores(config, true, true)
+	// the same retriever is used to verify the backup, so that it can reuse the
+	// passphrase that was just entered for the new key instead of prompting again
+	retriever := k.getRetriever()
+	ks, err := k.getKeyStores(config, retriever, true, true)
 	if err != nil {
 		return err
 	}
@@ -191,10 +270,109 @@ func (k *keyCommander) keysGenerateRootKey(cmd *cobra.Command, args []string) er
 		return fmt.Errorf("Failed to create a new root key: %v", err)
 	}
 
+	if k.generateBackupDir != "" {
+		err = backupKey(config.GetString("trust_dir"), k.generateBackupDir, pubKey.ID(), retriever)
+		if err != nil {
+			// don't leave behind a root key that has not been backed up
+			cs.RemoveKey(pubKey.ID())
+			return fmt.Errorf("Failed to back up the new root key, so it has been removed: %v", err)
+		}
+		if !k.generateEmitJSON {
+			cmd.Printf("Wrote an encrypted backup of the root key to %s\n", k.generateBackupDir)
+		}
+	}
+
+	if k.generateEmitJSON {
+		result, err := describeGeneratedKey(ks, pubKey)
+		if err != nil {
+			return err
+		}
+		result.BackupDir = k.generateBackupDir
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.Println(string(out))
+		return nil
+	}
+
 	cmd.Printf("Generated new %s root key with keyID: %s\n", algorithm, pubKey.ID())
 	return nil
 }
 
+// allowedRootKeyAlgorithms are the algorithms root keys can be generated with
+var allowedRootKeyAlgorithms = map[string]bool{
+	data.ECDSAKey: true,
+	data.RSAKey:   true,
+}
+
+// getRootKeyAlgorithm returns the algorithm to generate root keys with when
+// none is given on the command line, as set by root_key_algorithm in the
+// config.  It defaults to ecdsa.
+func getRootKeyAlgorithm(config *viper.Viper) (string, error) {
+	algorithm := strings.ToLower(config.GetString("root_key_algorithm"))
+	if algorithm == "" {
+		return data.ECDSAKey, nil
+	}
+	if !allowedRootKeyAlgorithms[algorithm] {
+		return "", fmt.Errorf(
+			"invalid root_key_algorithm %q in the config, possible values are: RSA, ECDSA",
+			config.GetString("root_key_algorithm"))
+	}
+	return algorithm, nil
+}
+
+// generatedKey describes a newly generated key for `notary key generate --emit-json`
+type generatedKey struct {
+	KeyID     string   `json:"key_id"`
+	Role      string   `json:"role"`
+	Algorithm string   `json:"algorithm"`
+	KeySize   int      `json:"key_size"`
+	KeyPath   string   `json:"key_path,omitempty"`
+	Locations []string `json:"locations"`
+	BackupDir string   `json:"backup_dir,omitempty"`
+}
+
+// describeGeneratedKey looks up where the newly generated key has been stored
+func describeGeneratedKey(keyStores []trustmanager.KeyStore, pubKey data.PublicKey) (*generatedKey, error) {
+	keySize, err := publicKeySize(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	result := &generatedKey{
+		KeyID:     pubKey.ID(),
+		Role:      data.CanonicalRootRole,
+		Algorithm: pubKey.Algorithm(),
+		KeySize:   keySize,
+		Locations: []string{},
+	}
+	for _, keyStore := range keyStores {
+		if _, err := keyStore.GetKeyInfo(pubKey.ID()); err != nil {
+			continue
+		}
+		result.Locations = append(result.Locations, keyStore.Name())
+		if _, ok := keyStore.(*trustmanager.GenericKeyStore); ok {
+			result.KeyPath = filepath.Join(keyStore.Name(), pubKey.ID()+"."+notary.KeyExtension)
+		}
+	}
+	return result, nil
+}
+
+// publicKeySize returns the size in bits of an RSA or ECDSA public key
+func publicKeySize(pubKey data.PublicKey) (int, error) {
+	parsed, err := x509.ParsePKIXPublicKey(pubKey.Public())
+	if err != nil {
+		return 0, err
+	}
+	switch key := parsed.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen(), nil
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize, nil
+	}
+	return 0, fmt.Errorf("unsupported public key type %s", pubKey.Algorithm())
+}
+
 func (k *keyCommander) keysRotate(cmd *cobra.Command, args []string) error {
 	if len(args) < 2 {
 		cmd.Usage()
@@ -207,6 +385,9 @@ func (k *keyCommander) keysRotate(cmd *cobra.Command, args []string) error {
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 	rotateKeyRole := args[1]
 
 	rt, err := getTransport(config, gun, admin)
@@ -227,6 +408,9 @@ func (k *keyCommander) keysRotate(cmd *cobra.Command, args []string) error {
 	}
 
 	if rotateKeyRole == data.CanonicalRootRole {
+		if err := checkConfirmationInput(cmd, k.input, k.requireTTY); err != nil {
+			return err
+		}
 		cmd.Print("Warning: you are about to rotate your root key.\n\n" +
 			"You must use your old key to sign this root rotation. We recommend that\n" +
 			"you sign all your future root changes with this key as well, so that\n" +
@@ -234,7 +418,9 @@ func (k *keyCommander) keysRotate(cmd *cobra.Command, args []string) error {
 			"this key after rotating.\n\n" +
 			"Are you sure you want to proceed?  (yes/no)  ")
 
-		if !askConfirm(k.input) {
+		if assumeYes(cmd) {
+			cmd.Println("\nConfirmed `yes` from flag")
+		} else if !askConfirm(k.input) {
 			fmt.Fprintln(cmd.Out(), "\nAborting action.")
 			return nil
 		}
@@ -243,8 +429,11 @@ func (k *keyCommander) keysRotate(cmd *cobra.Command, args []string) error {
 	return nRepo.RotateKey(rotateKeyRole, k.rotateKeyServerManaged)
 }
 
+// removeKeyInteractively asks which key to remove if more than one matches the
+// key ID, and confirms the removal.  If assumeYes is set, nothing is read from
+// in: the removal is confirmed, and more than one matching key is an error.
 func removeKeyInteractively(keyStores []trustmanager.KeyStore, keyID string,
-	in io.Reader, out io.Writer) error {
+	in io.Reader, out io.Writer, assumeYes bool) error {
 
 	var foundKeys [][]string
 	var storesByIndex []trustmanager.KeyStore
@@ -263,6 +452,11 @@ func removeKeyInteractively(keyStores []trustmanager.KeyStore, keyID string,
 		return fmt.Errorf("No key with ID %s found.", keyID)
 	}
 
+	if len(foundKeys) > 1 && assumeYes {
+		return fmt.Errorf(
+			"Found more than one key with ID %s, please run without --yes to choose which to remove.", keyID)
+	}
+
 	if len(foundKeys) > 1 {
 		for {
 			// ask the user for which key to delete
@@ -293,7 +487,9 @@ func removeKeyInteractively(keyStores []trustmanager.KeyStore, keyID string,
 
 	fmt.Fprintf(out, "Are you sure you want to remove %s?  (yes/no)  ",
 		keyDescription)
-	if !askConfirm(in) {
+	if assumeYes {
+		fmt.Fprintln(out, "\nConfirmed `yes` from flag")
+	} else if !askConfirm(in) {
 		fmt.Fprintln(out, "\nAborting action.")
 		return nil
 	}
@@ -306,6 +502,36 @@ func removeKeyInteractively(keyStores []trustmanager.KeyStore, keyID string,
 	return nil
 }
 
+// backupKey copies the encrypted private key with the given ID from the trust
+// directory to backupDir, and checks that the copy can be decrypted using the
+// passphrase retriever.  If the check fails, the copy is removed.
+func backupKey(trustDir, backupDir, keyID string, retriever notary.PassRetriever) error {
+	fileStore, err := store.NewPrivateKeyFileStorage(trustDir, notary.KeyExtension)
+	if err != nil {
+		return err
+	}
+	pemBytes, err := fileStore.Get(keyID)
+	if err != nil {
+		return err
+	}
+	backupStore, err := store.NewPrivateSimpleFileStore(backupDir, notary.KeyExtension)
+	if err != nil {
+		return err
+	}
+	if err := backupStore.Set(keyID, pemBytes); err != nil {
+		return err
+	}
+	privKey, _, err := trustmanager.NewGenericKeyStore(backupStore, retriever).GetKey(keyID)
+	if err == nil && privKey.ID() != keyID {
+		err = fmt.Errorf("backup contains key %s instead of %s", privKey.ID(), keyID)
+	}
+	if err != nil {
+		backupStore.Remove(keyID)
+		return fmt.Errorf("could not verify backup: %v", err)
+	}
+	return nil
+}
+
 // keyRemove deletes a private key based on ID
 func (k *keyCommander) keyRemove(cmd *cobra.Command, args []string) error {
 	if len(args) < 1 {
@@ -317,7 +543,7 @@ func (k *keyCommander) keyRemove(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	ks, err := k.getKeyStores(config, true, false)
+	ks, err := k.getKeyStores(config, k.getRetriever(), true, false)
 	if err != nil {
 		return err
 	}
@@ -327,8 +553,11 @@ func (k *keyCommander) keyRemove(cmd *cobra.Command, args []string) error {
 	if len(keyID) != notary.Sha256HexSize {
 		return fmt.Errorf("invalid key ID provided: %s", keyID)
 	}
+	if err := checkConfirmationInput(cmd, k.input, k.requireTTY); err != nil {
+		return err
+	}
 	cmd.Println("")
-	err = removeKeyInteractively(ks, keyID, k.input, cmd.Out())
+	err = removeKeyInteractively(ks, keyID, k.input, cmd.Out(), assumeYes(cmd))
 	cmd.Println("")
 	return err
 }
@@ -339,12 +568,17 @@ func (k *keyCommander) keyPassphraseChange(cmd *cobra.Command, args []string) er
 		cmd.Usage()
 		return fmt.Errorf("must specify the key ID of the key to change the passphrase of")
 	}
+	// --password-file would be used for both the old and the new passphrase,
+	// so the key would be re-encrypted with the passphrase it already has
+	if passwordFile, err := cmd.Flags().GetString("password-file"); err == nil && passwordFile != "" {
+		return fmt.Errorf("--password-file cannot be used to change a passphrase, since it would also provide the new passphrase")
+	}
 
 	config, err := k.configGetter()
 	if err != nil {
 		return err
 	}
-	ks, err := k.getKeyStores(config, true, false)
+	ks, err := k.getKeyStores(config, k.getRetriever(), true, false)
 	if err != nil {
 		return err
 	}
@@ -407,6 +641,12 @@ func (k *keyCommander) importKeys(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if k.keysImportGUN != "" {
+		if err := validateGUN(k.keysImportGUN); err != nil {
+			return err
+		}
+	}
+
 	directory := config.GetString("trust_dir")
 	importers, err := getImporters(directory, k.getRetriever())
 	if err != nil {
@@ -439,6 +679,12 @@ func (k *keyCommander) exportKeys(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	for _, gun := range k.exportGUNs {
+		if err := validateGUN(gun); err != nil {
+			return err
+		}
+	}
+
 	if k.outFile == "" {
 		out = cmd.Out()
 	} else {
@@ -466,20 +712,11 @@ func (k *keyCommander) exportKeys(cmd *cobra.Command, args []string) error {
 		return utils.ExportKeysByID(out, fileStore, k.exportKeyIDs)
 	}
 	// export everything
-	keys := fileStore.ListFiles()
-	for _, k := range keys {
-		err := utils.ExportKeys(out, fileStore, k)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return utils.ExportAllKeys(out, fileStore)
 }
 
-func (k *keyCommander) getKeyStores(
-	config *viper.Viper, withHardware, hardwareBackup bool) ([]trustmanager.KeyStore, error) {
-
-	retriever := k.getRetriever()
+func (k *keyCommander) getKeyStores(config *viper.Viper, retriever notary.PassRetriever,
+	withHardware, hardwareBackup bool) ([]trustmanager.KeyStore, error) {
 
 	directory := config.GetString("trust_dir")
 	fileKeyStore, err := trustmanager.NewKeyFileStore(directory, retriever)
@@ -3,12 +3,14 @@ package main
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -40,7 +42,7 @@ func TestRemoveIfNoKey(t *testing.T) {
 	setUp(t)
 	var buf bytes.Buffer
 	stores := []trustmanager.KeyStore{trustmanager.NewKeyMemoryStore(nil)}
-	err := removeKeyInteractively(stores, "12345", &buf, &buf)
+	err := removeKeyInteractively(stores, "12345", &buf, &buf, false)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "No key with ID")
 }
@@ -64,7 +66,7 @@ func TestRemoveOneKeyAbort(t *testing.T) {
 		var out bytes.Buffer
 		in := bytes.NewBuffer([]byte(noAnswer + "\n"))
 
-		err := removeKeyInteractively(stores, key.ID(), in, &out)
+		err := removeKeyInteractively(stores, key.ID(), in, &out, false)
 		require.NoError(t, err)
 		text, err := ioutil.ReadAll(&out)
 		require.NoError(t, err)
@@ -94,7 +96,7 @@ func TestRemoveOneKeyConfirm(t *testing.T) {
 		in := bytes.NewBuffer([]byte(yesAnswer + "\n"))
 
 		err = removeKeyInteractively(
-			[]trustmanager.KeyStore{store}, key.ID(), in, &out)
+			[]trustmanager.KeyStore{store}, key.ID(), in, &out, false)
 		require.NoError(t, err)
 		text, err := ioutil.ReadAll(&out)
 		require.NoError(t, err)
@@ -106,6 +108,38 @@ func TestRemoveOneKeyConfirm(t *testing.T) {
 	}
 }
 
+// If assumeYes is set, removeKeyInteractively removes a single matching key
+// without reading a confirmation, but refuses to choose between several
+// matching keys.
+func TestRemoveKeyAssumeYes(t *testing.T) {
+	setUp(t)
+	key, err := utils.GenerateED25519Key(rand.Reader)
+	require.NoError(t, err)
+
+	stores := []trustmanager.KeyStore{
+		trustmanager.NewKeyMemoryStore(ret),
+		trustmanager.NewKeyMemoryStore(ret),
+	}
+	err = stores[0].AddKey(trustmanager.KeyInfo{Role: data.CanonicalRootRole, Gun: ""}, key)
+	require.NoError(t, err)
+	err = stores[1].AddKey(trustmanager.KeyInfo{Role: data.CanonicalTargetsRole, Gun: "gun"}, key)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	err = removeKeyInteractively(stores, key.ID(), &bytes.Buffer{}, &out, true)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than one key")
+	require.Len(t, stores[0].ListKeys(), 1)
+	require.Len(t, stores[1].ListKeys(), 1)
+
+	out.Reset()
+	err = removeKeyInteractively(stores[:1], key.ID(), &bytes.Buffer{}, &out, true)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "Confirmed `yes` from flag")
+	require.Contains(t, out.String(), "Deleted "+key.ID())
+	require.Len(t, stores[0].ListKeys(), 0)
+}
+
 // If there is more than one key, removeKeyInteractively will ask which key to
 // delete and will do so over and over until the user quits if the answer is
 // invalid.
@@ -129,7 +163,7 @@ func TestRemoveMultikeysInvalidInput(t *testing.T) {
 
 	var out bytes.Buffer
 
-	err = removeKeyInteractively(stores, key.ID(), in, &out)
+	err = removeKeyInteractively(stores, key.ID(), in, &out, false)
 	require.Error(t, err)
 	text, err := ioutil.ReadAll(&out)
 	require.NoError(t, err)
@@ -178,7 +212,7 @@ func TestRemoveMultikeysAbortChoice(t *testing.T) {
 
 	var out bytes.Buffer
 
-	err = removeKeyInteractively(stores, key.ID(), in, &out)
+	err = removeKeyInteractively(stores, key.ID(), in, &out, false)
 	require.NoError(t, err) // no error to abort deleting
 	text, err := ioutil.ReadAll(&out)
 	require.NoError(t, err)
@@ -217,7 +251,7 @@ func TestRemoveMultikeysRemoveOnlyChosenKey(t *testing.T) {
 
 	var out bytes.Buffer
 
-	err = removeKeyInteractively(stores, key.ID(), in, &out)
+	err = removeKeyInteractively(stores, key.ID(), in, &out, false)
 	require.NoError(t, err)
 	text, err := ioutil.ReadAll(&out)
 	require.NoError(t, err)
@@ -804,3 +838,295 @@ func TestImportKeysNonexistentFile(t *testing.T) {
 	err = k.importKeys(&cobra.Command{}, []string{"Idontexist"})
 	require.Error(t, err)
 }
+
+// If --require-tty is passed, neither key removal nor root key rotation will
+// read a confirmation from input that is not a terminal
+func TestRequireTTYRefusesNonTerminalInput(t *testing.T) {
+	setUp(t)
+	tempBaseDir, err := ioutil.TempDir("/tmp", "notary-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempBaseDir)
+
+	k := &keyCommander{
+		configGetter: func() (*viper.Viper, error) {
+			v := viper.New()
+			v.SetDefault("trust_dir", tempBaseDir)
+			return v, nil
+		},
+		getRetriever: func() notary.PassRetriever { return ret },
+		input:        bytes.NewBuffer([]byte("yes\n")),
+		requireTTY:   true,
+	}
+
+	err = k.keyRemove(&cobra.Command{}, []string{strings.Repeat("a", notary.Sha256HexSize)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "STDIN is not a terminal")
+
+	err = k.keysRotate(&cobra.Command{}, []string{"docker.com/notary", data.CanonicalRootRole})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "STDIN is not a terminal")
+
+	// a file is not a terminal either
+	f, err := ioutil.TempFile(tempBaseDir, "input")
+	require.NoError(t, err)
+	defer f.Close()
+	require.False(t, isTerminal(f))
+}
+
+// Generating a root key with --backup-dir writes a decryptable copy of the
+// encrypted key to the backup directory
+func TestGenerateRootKeyWithBackup(t *testing.T) {
+	setUp(t)
+	tempBaseDir, err := ioutil.TempDir("/tmp", "notary-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempBaseDir)
+	backupDir := filepath.Join(tempBaseDir, "backup")
+
+	k := &keyCommander{
+		configGetter: func() (*viper.Viper, error) {
+			v := viper.New()
+			v.SetDefault("trust_dir", tempBaseDir)
+			return v, nil
+		},
+		getRetriever:      func() notary.PassRetriever { return ret },
+		generateBackupDir: backupDir,
+	}
+	require.NoError(t, k.keysGenerateRootKey(&cobra.Command{}, []string{}))
+
+	fileStore, err := store.NewPrivateKeyFileStorage(tempBaseDir, notary.KeyExtension)
+	require.NoError(t, err)
+	keyIDs := fileStore.ListFiles()
+	require.Len(t, keyIDs, 1)
+
+	backupStore, err := store.NewPrivateSimpleFileStore(backupDir, notary.KeyExtension)
+	require.NoError(t, err)
+	require.Equal(t, keyIDs, backupStore.ListFiles())
+
+	privKey, role, err := trustmanager.NewGenericKeyStore(backupStore, ret).GetKey(keyIDs[0])
+	require.NoError(t, err)
+	require.Equal(t, data.CanonicalRootRole, role)
+	require.Equal(t, keyIDs[0], privKey.ID())
+}
+
+// Verifying the backup reuses the passphrase that was entered for the new root
+// key, rather than prompting for it again
+func TestGenerateRootKeyBackupDoesNotPromptAgain(t *testing.T) {
+	setUp(t)
+	tempBaseDir, err := ioutil.TempDir("/tmp", "notary-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempBaseDir)
+
+	prompts := 0
+	k := &keyCommander{
+		configGetter: func() (*viper.Viper, error) {
+			v := viper.New()
+			v.SetDefault("trust_dir", tempBaseDir)
+			return v, nil
+		},
+		// like the prompting retriever, every new retriever starts with an
+		// empty passphrase cache
+		getRetriever: func() notary.PassRetriever {
+			var cached string
+			return func(string, string, bool, int) (string, bool, error) {
+				if cached == "" {
+					prompts++
+					cached = "passphrase"
+				}
+				return cached, false, nil
+			}
+		},
+		generateBackupDir: filepath.Join(tempBaseDir, "backup"),
+	}
+	require.NoError(t, k.keysGenerateRootKey(&cobra.Command{}, []string{}))
+	require.Equal(t, 1, prompts)
+}
+
+// If the backup cannot be written, the newly generated root key is removed
+func TestGenerateRootKeyBackupFailureRemovesKey(t *testing.T) {
+	setUp(t)
+	tempBaseDir, err := ioutil.TempDir("/tmp", "notary-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempBaseDir)
+
+	// the backup directory is a file, so it can't be created
+	backupDir := filepath.Join(tempBaseDir, "backup")
+	require.NoError(t, ioutil.WriteFile(backupDir, []byte("not a directory"), 0600))
+
+	k := &keyCommander{
+		configGetter: func() (*viper.Viper, error) {
+			v := viper.New()
+			v.SetDefault("trust_dir", tempBaseDir)
+			return v, nil
+		},
+		getRetriever:      func() notary.PassRetriever { return ret },
+		generateBackupDir: backupDir,
+	}
+	err = k.keysGenerateRootKey(&cobra.Command{}, []string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Failed to back up the new root key")
+
+	fileStore, err := store.NewPrivateKeyFileStorage(tempBaseDir, notary.KeyExtension)
+	require.NoError(t, err)
+	require.Empty(t, fileStore.ListFiles())
+}
+
+// key list --output-file writes the listing to the given file, creating any
+// missing parent directories, and writes nothing to the command's output
+func TestKeysListOutputFile(t *testing.T) {
+	setUp(t)
+	tempBaseDir, err := ioutil.TempDir("/tmp", "notary-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempBaseDir)
+
+	fileStore, err := store.NewPrivateKeyFileStorage(tempBaseDir, notary.KeyExtension)
+	require.NoError(t, err)
+	key, err := utils.GenerateECDSAKey(rand.Reader)
+	require.NoError(t, err)
+	err = trustmanager.NewGenericKeyStore(fileStore, ret).AddKey(
+		trustmanager.KeyInfo{Role: data.CanonicalRootRole}, key)
+	require.NoError(t, err)
+
+	outFile := filepath.Join(tempBaseDir, "reports", "keys.txt")
+	k := &keyCommander{
+		configGetter: func() (*viper.Viper, error) {
+			v := viper.New()
+			v.SetDefault("trust_dir", tempBaseDir)
+			return v, nil
+		},
+		getRetriever:   func() notary.PassRetriever { return ret },
+		listOutputFile: outFile,
+	}
+	c := &cobra.Command{}
+	var out bytes.Buffer
+	c.SetOutput(&out)
+	require.NoError(t, k.keysList(c, []string{}))
+	require.Empty(t, out.String())
+
+	listing, err := ioutil.ReadFile(outFile)
+	require.NoError(t, err)
+	require.Contains(t, string(listing), "KEY ID")
+	require.Contains(t, string(listing), key.ID())
+}
+
+// key generate --emit-json describes the generated key as JSON
+func TestGenerateRootKeyEmitJSON(t *testing.T) {
+	setUp(t)
+	tempBaseDir, err := ioutil.TempDir("/tmp", "notary-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempBaseDir)
+
+	k := &keyCommander{
+		configGetter: func() (*viper.Viper, error) {
+			v := viper.New()
+			v.SetDefault("trust_dir", tempBaseDir)
+			return v, nil
+		},
+		getRetriever:     func() notary.PassRetriever { return ret },
+		generateEmitJSON: true,
+	}
+	for algorithm, size := range map[string]int{data.ECDSAKey: 256, data.RSAKey: notary.MinRSABitSize} {
+		c := &cobra.Command{}
+		var out bytes.Buffer
+		c.SetOutput(&out)
+		require.NoError(t, k.keysGenerateRootKey(c, []string{algorithm}))
+
+		var result generatedKey
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.Equal(t, data.CanonicalRootRole, result.Role)
+		require.Equal(t, algorithm, result.Algorithm)
+		require.Equal(t, size, result.KeySize)
+		require.Len(t, result.Locations, 1)
+
+		// the key path points at the stored key
+		_, err := os.Stat(result.KeyPath)
+		require.NoError(t, err)
+		require.Equal(t, result.KeyID+"."+notary.KeyExtension, filepath.Base(result.KeyPath))
+	}
+}
+
+// key generate uses the root_key_algorithm from the config when no algorithm
+// is given on the command line
+func TestGenerateRootKeyConfiguredAlgorithm(t *testing.T) {
+	setUp(t)
+	tempBaseDir, err := ioutil.TempDir("/tmp", "notary-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempBaseDir)
+
+	configuredAlgorithm := "RSA"
+	k := &keyCommander{
+		configGetter: func() (*viper.Viper, error) {
+			v := viper.New()
+			v.SetDefault("trust_dir", tempBaseDir)
+			v.SetDefault("root_key_algorithm", configuredAlgorithm)
+			return v, nil
+		},
+		getRetriever:     func() notary.PassRetriever { return ret },
+		generateEmitJSON: true,
+	}
+
+	for _, testCase := range []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{}, expected: data.RSAKey},
+		// the command line takes precedence
+		{args: []string{data.ECDSAKey}, expected: data.ECDSAKey},
+	} {
+		c := &cobra.Command{}
+		var out bytes.Buffer
+		c.SetOutput(&out)
+		require.NoError(t, k.keysGenerateRootKey(c, testCase.args))
+
+		var result generatedKey
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.Equal(t, testCase.expected, result.Algorithm)
+	}
+
+	configuredAlgorithm = "ed25519"
+	err = k.keysGenerateRootKey(&cobra.Command{}, []string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid root_key_algorithm")
+}
+
+// key list --output json prints only the JSON listing, so that it can be parsed, and
+// an unknown output format is an error
+func TestKeysListJSONOutput(t *testing.T) {
+	setUp(t)
+	tempBaseDir, err := ioutil.TempDir("/tmp", "notary-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempBaseDir)
+
+	fileStore, err := store.NewPrivateKeyFileStorage(tempBaseDir, notary.KeyExtension)
+	require.NoError(t, err)
+	key, err := utils.GenerateECDSAKey(rand.Reader)
+	require.NoError(t, err)
+	err = trustmanager.NewGenericKeyStore(fileStore, ret).AddKey(
+		trustmanager.KeyInfo{Role: data.CanonicalTargetsRole, Gun: "docker.com/notary"}, key)
+	require.NoError(t, err)
+
+	k := &keyCommander{
+		configGetter: func() (*viper.Viper, error) {
+			v := viper.New()
+			v.SetDefault("trust_dir", tempBaseDir)
+			return v, nil
+		},
+		getRetriever:     func() notary.PassRetriever { return ret },
+		listOutputFormat: "json",
+	}
+	c := &cobra.Command{}
+	var out bytes.Buffer
+	c.SetOutput(&out)
+	require.NoError(t, k.keysList(c, []string{}))
+
+	var list jsonKeyList
+	require.NoError(t, json.Unmarshal(out.Bytes(), &list))
+	require.Len(t, list.Keys, 1)
+	require.Equal(t, key.ID(), list.Keys[0].KeyID)
+	require.Equal(t, "docker.com/notary", list.Keys[0].GUN)
+	require.Equal(t, data.CanonicalTargetsRole, list.Keys[0].Role)
+
+	k.listOutputFormat = "yaml"
+	err = k.keysList(c, []string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid output format")
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/Sirupsen/logrus"
@@ -15,26 +17,55 @@ import (
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 const (
 	configDir        = ".notary/"
 	defaultServerURL = "https://notary-server:4443"
+	// readOnlyEnvVar can be set to a true value to run notary in read-only mode
+	readOnlyEnvVar = "NOTARY_READONLY"
 )
 
+// readOnlyCommands are the commands, identified by their command path, that do
+// not change keys, staged changes or remote trust data, and so may still be run
+// in read-only mode.  Any other command is refused.  Note that list, lookup and
+// verify still download the latest metadata and cache it in the trust
+// directory, trusting the root of a new GUN on first use.
+var readOnlyCommands = map[string]bool{
+	"notary":                    true,
+	"notary help":               true,
+	"notary version":            true,
+	"notary list":               true,
+	"notary lookup":             true,
+	"notary status":             true,
+	"notary verify":             true,
+	"notary key":                true,
+	"notary key list":           true,
+	"notary key export":         true,
+	"notary delegation":         true,
+	"notary delegation list":    true,
+	"notary completion":         true,
+	"notary completion bash":    true,
+	"notary completion key-ids": true,
+	"notary completion guns":    true,
+}
+
 type usageTemplate struct {
-	Use   string
-	Short string
-	Long  string
+	Use     string
+	Short   string
+	Long    string
+	Example string
 }
 
 type cobraRunE func(cmd *cobra.Command, args []string) error
 
 func (u usageTemplate) ToCommand(run cobraRunE) *cobra.Command {
 	c := cobra.Command{
-		Use:   u.Use,
-		Short: u.Short,
-		Long:  u.Long,
+		Use:     u.Use,
+		Short:   u.Short,
+		Long:    u.Long,
+		Example: u.Example,
 	}
 	if run != nil {
 		// newer versions of cobra support a run function that returns an error,
@@ -69,6 +100,10 @@ type notaryCommander struct {
 	tlsCAFile   string
 	tlsCertFile string
 	tlsKeyFile  string
+
+	passwordFile string
+	readOnly     bool
+	assumeYes    bool
 }
 
 func (n *notaryCommander) parseConfig() (*viper.Viper, error) {
@@ -129,6 +164,18 @@ func (n *notaryCommander) parseConfig() (*viper.Viper, error) {
 		config.Set("remote_server.url", n.remoteTrustServer)
 	}
 
+	// Fail early if we were given a passphrase file we cannot use, since
+	// retriever errors are not surfaced to the user when decrypting keys
+	if n.passwordFile != "" {
+		if _, err := readPassphraseFile(n.passwordFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := getRootKeyAlgorithm(config); err != nil {
+		return nil, err
+	}
+
 	// Expands all the possible ~/ that have been given, either through -d or config
 	// If there is no error, use it, if not, just attempt to use whatever the user gave us
 	expandedTrustDir, err := homedir.Expand(config.GetString("trust_dir"))
@@ -148,6 +195,8 @@ func (n *notaryCommander) GetCommand() *cobra.Command {
 		SilenceUsage:  true, // we don't want to print out usage for EVERY error
 		SilenceErrors: true, // we do our own error reporting with fatalf
 		Run:           func(cmd *cobra.Command, args []string) { cmd.Usage() },
+		// this is inherited by all subcommands
+		PersistentPreRunE: n.checkReadOnly,
 	}
 	notaryCmd.SetOutput(os.Stdout)
 	notaryCmd.AddCommand(&cobra.Command{
@@ -169,25 +218,37 @@ func (n *notaryCommander) GetCommand() *cobra.Command {
 	notaryCmd.PersistentFlags().StringVar(&n.tlsCAFile, "tlscacert", "", "Trust certs signed only by this CA")
 	notaryCmd.PersistentFlags().StringVar(&n.tlsCertFile, "tlscert", "", "Path to TLS certificate file")
 	notaryCmd.PersistentFlags().StringVar(&n.tlsKeyFile, "tlskey", "", "Path to TLS key file")
+	notaryCmd.PersistentFlags().StringVar(&n.passwordFile, "password-file", "",
+		"Path to a file containing the passphrase to use for all keys (takes precedence over passphrase environment variables)")
+	notaryCmd.PersistentFlags().BoolVar(&n.readOnly, "read-only", false,
+		"Refuse to run any command that would change keys, staged changes or remote trust data (can also be set with "+readOnlyEnvVar+")")
+	notaryCmd.PersistentFlags().BoolVarP(&n.assumeYes, "yes", "y", false,
+		"Answer yes to all confirmation questions, instead of reading the answers from STDIN")
+
+	getRetriever := func() notary.PassRetriever {
+		return n.passphraseFileRetriever(n.getRetriever())
+	}
 
 	cmdKeyGenerator := &keyCommander{
 		configGetter: n.parseConfig,
-		getRetriever: n.getRetriever,
+		getRetriever: getRetriever,
 		input:        os.Stdin,
 	}
 
 	cmdDelegationGenerator := &delegationCommander{
 		configGetter: n.parseConfig,
-		retriever:    n.getRetriever(),
+		retriever:    getRetriever(),
+		input:        os.Stdin,
 	}
 
 	cmdTUFGenerator := &tufCommander{
 		configGetter: n.parseConfig,
-		retriever:    n.getRetriever(),
+		retriever:    getRetriever(),
 	}
 
 	notaryCmd.AddCommand(cmdKeyGenerator.GetCommand())
 	notaryCmd.AddCommand(cmdDelegationGenerator.GetCommand())
+	notaryCmd.AddCommand((&completionCommander{configGetter: n.parseConfig}).GetCommand())
 
 	cmdTUFGenerator.AddToCommand(&notaryCmd)
 
@@ -219,6 +280,30 @@ func askConfirm(input io.Reader) bool {
 	return false
 }
 
+// assumeYes returns whether --yes was passed to the command, in which case
+// confirmation questions are answered without reading from STDIN
+func assumeYes(cmd *cobra.Command) bool {
+	yes, err := cmd.Flags().GetBool("yes")
+	return err == nil && yes
+}
+
+// checkConfirmationInput returns an error if requireTTY is set (by a command's
+// --require-tty flag) and the input that confirmations will be read from is not
+// a terminal, so that stray piped input cannot confirm a destructive action.
+// If --yes was passed, no confirmation is read, so there is nothing to check.
+func checkConfirmationInput(cmd *cobra.Command, input io.Reader, requireTTY bool) error {
+	if requireTTY && !assumeYes(cmd) && !isTerminal(input) {
+		return fmt.Errorf("refusing to read confirmation: --require-tty was specified but STDIN is not a terminal")
+	}
+	return nil
+}
+
+// isTerminal returns whether the given input is a terminal
+func isTerminal(input io.Reader) bool {
+	f, ok := input.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
 func getPassphraseRetriever() notary.PassRetriever {
 	baseRetriever := passphrase.PromptRetriever()
 	env := map[string]string{
@@ -243,6 +328,65 @@ func getPassphraseRetriever() notary.PassRetriever {
 	}
 }
 
+// checkReadOnly returns an error if notary is running in read-only mode, either
+// because of the --read-only flag or the NOTARY_READONLY environment variable,
+// and the command being run could change keys, staged changes or remote trust
+// data.
+func (n *notaryCommander) checkReadOnly(cmd *cobra.Command, args []string) error {
+	readOnly := n.readOnly
+	if env := os.Getenv(readOnlyEnvVar); env != "" && !readOnly {
+		var err error
+		if readOnly, err = strconv.ParseBool(env); err != nil {
+			return fmt.Errorf("invalid value for %s: %s", readOnlyEnvVar, env)
+		}
+	}
+	if !readOnly {
+		return nil
+	}
+	allowed := readOnlyCommands[cmd.CommandPath()]
+	// status can also be used to unstage or reset changes
+	if cmd.CommandPath() == "notary status" && (cmd.Flags().Changed("unstage") || cmd.Flags().Changed("reset")) {
+		allowed = false
+	}
+	if !allowed {
+		return fmt.Errorf("refusing to run `%s` in read-only mode", cmd.CommandPath())
+	}
+	return nil
+}
+
+// passphraseFileRetriever returns a PassRetriever that provides the contents of
+// the --password-file for every key if that flag was given, and otherwise
+// defers to the fallback retriever.  The file is read when a passphrase is
+// requested rather than here, since retrievers are created before the command
+// line flags have been parsed.
+func (n *notaryCommander) passphraseFileRetriever(fallback notary.PassRetriever) notary.PassRetriever {
+	return func(keyName string, alias string, createNew bool, numAttempts int) (string, bool, error) {
+		if n.passwordFile == "" {
+			return fallback(keyName, alias, createNew, numAttempts)
+		}
+		pass, err := readPassphraseFile(n.passwordFile)
+		if err != nil {
+			return "", true, err
+		}
+		return pass, numAttempts > 1, nil
+	}
+}
+
+// readPassphraseFile reads a passphrase from a file, trimming a single trailing
+// newline.  An empty passphrase is an error.
+func readPassphraseFile(passwordFile string) (string, error) {
+	contents, err := ioutil.ReadFile(passwordFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read passphrase file: %v", err)
+	}
+	pass := strings.TrimSuffix(string(contents), "\n")
+	pass = strings.TrimSuffix(pass, "\r")
+	if pass == "" {
+		return "", fmt.Errorf("passphrase file %s is empty", passwordFile)
+	}
+	return pass, nil
+}
+
 // Set the logging level to fatal on default, or the most specific level the user specified (debug or error)
 func (n *notaryCommander) setVerbosityLevel() {
 	if n.debug {
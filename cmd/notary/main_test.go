@@ -614,3 +614,146 @@ func TestPassphraseRetrieverDelegationRoleCaching(t *testing.T) {
 	_, _, err = retriever("key", data.CanonicalSnapshotRole, false, 0)
 	require.Error(t, err)
 }
+
+// a passphrase file, if provided, is used for all keys in preference to the fallback retriever
+func TestPassphraseFileRetriever(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "passphrase-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	passFile := filepath.Join(tempDir, "passphrase")
+	require.NoError(t, ioutil.WriteFile(passFile, []byte("file_passphrase\n"), 0600))
+
+	commander := &notaryCommander{}
+	retriever := commander.passphraseFileRetriever(passphrase.ConstantRetriever("fallback"))
+
+	// no file given, so the fallback is used
+	pass, giveup, err := retriever("key", data.CanonicalRootRole, false, 0)
+	require.NoError(t, err)
+	require.False(t, giveup)
+	require.Equal(t, "fallback", pass)
+
+	// with a file, the trailing newline is trimmed and it is used for every role
+	commander.passwordFile = passFile
+	for _, role := range []string{data.CanonicalRootRole, data.CanonicalTargetsRole, "targets/releases"} {
+		pass, giveup, err = retriever("key", role, false, 0)
+		require.NoError(t, err)
+		require.False(t, giveup)
+		require.Equal(t, "file_passphrase", pass)
+	}
+
+	// an empty file is an error
+	require.NoError(t, ioutil.WriteFile(passFile, []byte("\n"), 0600))
+	_, giveup, err = retriever("key", data.CanonicalRootRole, false, 0)
+	require.Error(t, err)
+	require.True(t, giveup)
+
+	// as is a missing file
+	commander.passwordFile = filepath.Join(tempDir, "idonotexist")
+	_, giveup, err = retriever("key", data.CanonicalRootRole, false, 0)
+	require.Error(t, err)
+	require.True(t, giveup)
+}
+
+// an unusable passphrase file is reported before any command is run
+func TestPassphraseFileErrorsPropagatedByCommands(t *testing.T) {
+	tempDir := tempDirWithConfig(t, "{}")
+	defer os.RemoveAll(tempDir)
+	configFile := filepath.Join(tempDir, "config.json")
+
+	cmd := NewNotaryCommand()
+	cmd.SetOutput(new(bytes.Buffer))
+	cmd.SetArgs([]string{"-c", configFile, "-d", tempDir,
+		"--password-file", filepath.Join(tempDir, "idonotexist"), "key", "list"})
+	err := cmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to read passphrase file")
+}
+
+// key passwd refuses a passphrase file, since it would also be used as the new
+// passphrase and the passphrase would not actually change
+func TestPassphraseFileRefusedByKeyPasswd(t *testing.T) {
+	tempDir := tempDirWithConfig(t, "{}")
+	defer os.RemoveAll(tempDir)
+	configFile := filepath.Join(tempDir, "config.json")
+	passFile := filepath.Join(tempDir, "passphrase")
+	require.NoError(t, ioutil.WriteFile(passFile, []byte("file_passphrase\n"), 0600))
+
+	cmd := NewNotaryCommand()
+	cmd.SetOutput(new(bytes.Buffer))
+	cmd.SetArgs([]string{"-c", configFile, "-d", tempDir,
+		"--password-file", passFile, "key", "passwd", strings.Repeat("a", notary.Sha256HexSize)})
+	err := cmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "--password-file cannot be used to change a passphrase")
+}
+
+// an invalid root_key_algorithm in the config is reported before any command is run
+func TestInvalidRootKeyAlgorithmInConfig(t *testing.T) {
+	tempDir := tempDirWithConfig(t, `{"root_key_algorithm": "dsa"}`)
+	defer os.RemoveAll(tempDir)
+	configFile := filepath.Join(tempDir, "config.json")
+
+	cmd := NewNotaryCommand()
+	cmd.SetOutput(new(bytes.Buffer))
+	cmd.SetArgs([]string{"-c", configFile, "-d", tempDir, "key", "list"})
+	err := cmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid root_key_algorithm")
+}
+
+// in read-only mode, commands that could modify trust data are refused before
+// doing anything, but commands that only read trust data still work
+func TestReadOnlyMode(t *testing.T) {
+	tempDir := tempDirWithConfig(t, "{}")
+	defer os.RemoveAll(tempDir)
+	configFile := filepath.Join(tempDir, "config.json")
+
+	run := func(args ...string) (string, error) {
+		b := new(bytes.Buffer)
+		cmd := NewNotaryCommand()
+		cmd.SetOutput(b)
+		cmd.SetArgs(append([]string{"-c", configFile, "-d", tempDir}, args...))
+		err := cmd.Execute()
+		return b.String(), err
+	}
+
+	for _, args := range [][]string{
+		{"key", "generate", "ecdsa"},
+		{"key", "remove", strings.Repeat("a", notary.Sha256HexSize)},
+		{"init", "gun"},
+		{"publish", "gun"},
+		{"delegation", "purge", "gun"},
+		{"status", "gun", "--reset"},
+	} {
+		_, err := run(append([]string{"--read-only"}, args...)...)
+		require.Error(t, err, "expected `notary %s` to fail in read-only mode", strings.Join(args, " "))
+		require.Contains(t, err.Error(), "read-only mode")
+	}
+
+	output, err := run("--read-only", "key", "list")
+	require.NoError(t, err)
+	require.Contains(t, output, "No signing keys found")
+
+	// nothing was generated by the refused commands
+	fileInfos, err := ioutil.ReadDir(filepath.Join(tempDir, notary.PrivDir))
+	if err == nil {
+		require.Len(t, fileInfos, 0)
+	}
+
+	// the environment variable has the same effect as the flag
+	require.NoError(t, os.Setenv(readOnlyEnvVar, "true"))
+	defer os.Unsetenv(readOnlyEnvVar)
+	_, err = run("key", "generate", "ecdsa")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "read-only mode")
+
+	require.NoError(t, os.Setenv(readOnlyEnvVar, "notabool"))
+	_, err = run("key", "list")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), readOnlyEnvVar)
+
+	require.NoError(t, os.Setenv(readOnlyEnvVar, "false"))
+	_, err = run("key", "generate", "ecdsa")
+	require.NoError(t, err)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"sort"
@@ -14,6 +15,7 @@ import (
 )
 
 const (
+	twoItemRow  = "%s\t%s\n"
 	fourItemRow = "%s\t%s\t%s\t%s\n"
 	fiveItemRow = "%s\t%s\t%s\t%s\t%s\n"
 )
@@ -93,9 +95,9 @@ func (k keyInfoSorter) Less(i, j int) bool {
 	return false
 }
 
-// Given a list of KeyStores in order of listing preference, pretty-prints the
-// root keys and then the signing keys.
-func prettyPrintKeys(keyStores []trustmanager.KeyStore, writer io.Writer) {
+// Given a list of KeyStores in order of listing preference, returns the info
+// for every key in every store, sorted with the root keys first.
+func getKeyInfos(keyStores []trustmanager.KeyStore) []keyInfo {
 	var info []keyInfo
 
 	for _, store := range keyStores {
@@ -109,13 +111,20 @@ func prettyPrintKeys(keyStores []trustmanager.KeyStore, writer io.Writer) {
 		}
 	}
 
+	sort.Stable(keyInfoSorter(info))
+	return info
+}
+
+// Given a list of KeyStores in order of listing preference, pretty-prints the
+// root keys and then the signing keys.
+func prettyPrintKeys(keyStores []trustmanager.KeyStore, writer io.Writer) {
+	info := getKeyInfos(keyStores)
+
 	if len(info) == 0 {
 		writer.Write([]byte("No signing keys found.\n"))
 		return
 	}
 
-	sort.Stable(keyInfoSorter(info))
-
 	tw := initTabWriter([]string{"ROLE", "GUN", "KEY ID", "LOCATION"}, writer)
 
 	for _, oneKeyInfo := range info {
@@ -131,6 +140,101 @@ func prettyPrintKeys(keyStores []trustmanager.KeyStore, writer io.Writer) {
 	tw.Flush()
 }
 
+// Given a list of KeyStores, returns the number of keys in them for each role,
+// and the total.  Keys are counted the same way as they are listed by
+// prettyPrintKeys, so a key stored in more than one location is counted once
+// for each location.
+func countKeys(keyStores []trustmanager.KeyStore) (map[string]int, int) {
+	counts := make(map[string]int)
+	total := 0
+	for _, store := range keyStores {
+		for _, keyIDInfo := range store.ListKeys() {
+			counts[keyIDInfo.Role]++
+			total++
+		}
+	}
+	return counts, total
+}
+
+// Given a list of KeyStores, pretty-prints the number of keys listed for each
+// role, with root keys first, followed by the total.
+func prettyPrintKeyCounts(keyStores []trustmanager.KeyStore, writer io.Writer) {
+	counts, total := countKeys(keyStores)
+
+	if total == 0 {
+		writer.Write([]byte("No signing keys found.\n"))
+		return
+	}
+
+	roles := make([]string, 0, len(counts))
+	for role := range counts {
+		if role != data.CanonicalRootRole {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	if counts[data.CanonicalRootRole] > 0 {
+		roles = append([]string{data.CanonicalRootRole}, roles...)
+	}
+
+	tw := initTabWriter([]string{"ROLE", "KEYS"}, writer)
+	for _, role := range roles {
+		fmt.Fprintf(tw, twoItemRow, role, fmt.Sprintf("%d", counts[role]))
+	}
+	fmt.Fprintf(tw, twoItemRow, "total", fmt.Sprintf("%d", total))
+	tw.Flush()
+}
+
+// --- printing keys as JSON ---
+
+type jsonKey struct {
+	Role     string `json:"role"`
+	GUN      string `json:"gun"`
+	KeyID    string `json:"key_id"`
+	Location string `json:"location"`
+}
+
+type jsonKeyList struct {
+	Keys []jsonKey `json:"keys"`
+}
+
+type jsonKeyCounts struct {
+	Roles map[string]int `json:"roles"`
+	Total int            `json:"total"`
+}
+
+// Given a list of KeyStores in order of listing preference, prints the keys as
+// a JSON object in the same order as prettyPrintKeys, without truncating any
+// of the values.
+func jsonPrintKeys(keyStores []trustmanager.KeyStore, writer io.Writer) error {
+	list := jsonKeyList{Keys: []jsonKey{}}
+	for _, oneKeyInfo := range getKeyInfos(keyStores) {
+		list.Keys = append(list.Keys, jsonKey{
+			Role:     oneKeyInfo.role,
+			GUN:      oneKeyInfo.gun,
+			KeyID:    oneKeyInfo.keyID,
+			Location: oneKeyInfo.location,
+		})
+	}
+	return jsonPrint(list, writer)
+}
+
+// Given a list of KeyStores, prints the number of keys for each role, and the
+// total, as a JSON object.
+func jsonPrintKeyCounts(keyStores []trustmanager.KeyStore, writer io.Writer) error {
+	counts, total := countKeys(keyStores)
+	return jsonPrint(jsonKeyCounts{Roles: counts, Total: total}, writer)
+}
+
+func jsonPrint(v interface{}, writer io.Writer) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(writer, string(out))
+	return err
+}
+
 // --- pretty printing targets ---
 
 type targetsSorter []*client.TargetWithRole
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"reflect"
@@ -142,6 +143,94 @@ func TestPrettyPrintRootAndSigningKeys(t *testing.T) {
 	}
 }
 
+// Given a list of key stores, the key counts should be pretty-printed by role,
+// with root first, followed by the total number of keys listed
+func TestPrettyPrintKeyCounts(t *testing.T) {
+	ret := passphrase.ConstantRetriever("pass")
+	keyStores := []trustmanager.KeyStore{
+		trustmanager.NewKeyMemoryStore(ret),
+		&otherMemoryStore{GenericKeyStore: *trustmanager.NewKeyMemoryStore(ret)},
+	}
+
+	keys := make([]data.PrivateKey, 3)
+	for i := 0; i < 3; i++ {
+		key, err := utils.GenerateED25519Key(rand.Reader)
+		require.NoError(t, err)
+		keys[i] = key
+	}
+
+	// the root key is in both key stores, so is counted twice
+	require.NoError(t, keyStores[0].AddKey(trustmanager.KeyInfo{Role: data.CanonicalRootRole}, keys[0]))
+	require.NoError(t, keyStores[1].AddKey(trustmanager.KeyInfo{Role: data.CanonicalRootRole}, keys[0]))
+	require.NoError(t, keyStores[0].AddKey(trustmanager.KeyInfo{Role: data.CanonicalTargetsRole, Gun: "gun"}, keys[1]))
+	require.NoError(t, keyStores[0].AddKey(trustmanager.KeyInfo{Role: data.CanonicalSnapshotRole, Gun: "gun"}, keys[2]))
+
+	var b bytes.Buffer
+	prettyPrintKeyCounts(keyStores, &b)
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+
+	expected := [][]string{
+		{"ROLE", "KEYS"},
+		{"----", "----"},
+		{data.CanonicalRootRole, "2"},
+		{data.CanonicalSnapshotRole, "1"},
+		{data.CanonicalTargetsRole, "1"},
+		{"total", "4"},
+	}
+	require.Len(t, lines, len(expected))
+	for i, line := range lines {
+		require.Equal(t, expected[i], strings.Fields(line))
+	}
+
+	// no keys at all
+	b.Reset()
+	prettyPrintKeyCounts([]trustmanager.KeyStore{trustmanager.NewKeyMemoryStore(ret)}, &b)
+	require.Equal(t, "No signing keys found.", strings.TrimSpace(b.String()))
+}
+
+// Keys printed as JSON are in the same order as the pretty-printed keys, and
+// their GUNs and locations are not truncated
+func TestJSONPrintKeys(t *testing.T) {
+	ret := passphrase.ConstantRetriever("pass")
+	keyStores := []trustmanager.KeyStore{
+		trustmanager.NewKeyMemoryStore(ret),
+		&otherMemoryStore{GenericKeyStore: *trustmanager.NewKeyMemoryStore(ret)},
+	}
+
+	keys := make([]data.PrivateKey, 2)
+	for i := 0; i < 2; i++ {
+		key, err := utils.GenerateED25519Key(rand.Reader)
+		require.NoError(t, err)
+		keys[i] = key
+	}
+	longGUN := strings.Repeat("/a", 30)
+	require.NoError(t, keyStores[0].AddKey(trustmanager.KeyInfo{Role: data.CanonicalTargetsRole, Gun: longGUN}, keys[1]))
+	require.NoError(t, keyStores[1].AddKey(trustmanager.KeyInfo{Role: data.CanonicalRootRole}, keys[0]))
+
+	var b bytes.Buffer
+	require.NoError(t, jsonPrintKeys(keyStores, &b))
+	var list jsonKeyList
+	require.NoError(t, json.Unmarshal(b.Bytes(), &list))
+	require.Equal(t, []jsonKey{
+		{Role: data.CanonicalRootRole, KeyID: keys[0].ID(), Location: keyStores[1].Name()},
+		{Role: data.CanonicalTargetsRole, GUN: longGUN, KeyID: keys[1].ID(), Location: keyStores[0].Name()},
+	}, list.Keys)
+
+	b.Reset()
+	require.NoError(t, jsonPrintKeyCounts(keyStores, &b))
+	var counts jsonKeyCounts
+	require.NoError(t, json.Unmarshal(b.Bytes(), &counts))
+	require.Equal(t, jsonKeyCounts{
+		Roles: map[string]int{data.CanonicalRootRole: 1, data.CanonicalTargetsRole: 1},
+		Total: 2,
+	}, counts)
+
+	// no keys is an empty list, not null
+	b.Reset()
+	require.NoError(t, jsonPrintKeys([]trustmanager.KeyStore{trustmanager.NewKeyMemoryStore(ret)}, &b))
+	require.Contains(t, b.String(), `"keys": []`)
+}
+
 // --- tests for pretty printing targets ---
 
 // If there are no targets, no table is printed, only a line saying that there
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -33,66 +35,85 @@ var cmdTUFListTemplate = usageTemplate{
 	Use:   "list [ GUN ]",
 	Short: "Lists targets for a remote trusted collection.",
 	Long:  "Lists all targets for a remote trusted collection identified by the Globally Unique Name. This is an online operation.",
+	Example: `  notary list docker.io/library/alpine
+  notary list docker.io/library/alpine --roles targets/releases`,
 }
 
 var cmdTUFAddTemplate = usageTemplate{
 	Use:   "add [ GUN ] <target> <file>",
 	Short: "Adds the file as a target to the trusted collection.",
 	Long:  "Adds the file as a target to the local trusted collection identified by the Globally Unique Name. This is an offline operation.  Please then use `publish` to push the changes to the remote trusted collection.",
+	Example: `  notary add docker.io/library/alpine v1.0 alpine-1.0.tar.gz
+  notary add docker.io/library/alpine v1.0 alpine-1.0.tar.gz --roles targets/releases --publish`,
 }
 
 var cmdTUFAddHashTemplate = usageTemplate{
-	Use:   "addhash [ GUN ] <target> <byte size> <hashes>",
-	Short: "Adds the byte size and hash(es) as a target to the trusted collection.",
-	Long:  "Adds the specified byte size and hash(es) as a target to the local trusted collection identified by the Globally Unique Name. This is an offline operation.  Please then use `publish` to push the changes to the remote trusted collection.",
+	Use:     "addhash [ GUN ] <target> <byte size> <hashes>",
+	Short:   "Adds the byte size and hash(es) as a target to the trusted collection.",
+	Long:    "Adds the specified byte size and hash(es) as a target to the local trusted collection identified by the Globally Unique Name. This is an offline operation.  Please then use `publish` to push the changes to the remote trusted collection.",
+	Example: `  notary addhash docker.io/library/alpine v1.0 1048576 --sha256 0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef`,
 }
 
 var cmdTUFRemoveTemplate = usageTemplate{
 	Use:   "remove [ GUN ] <target>",
 	Short: "Removes a target from a trusted collection.",
 	Long:  "Removes a target from the local trusted collection identified by the Globally Unique Name. This is an offline operation.  Please then use `publish` to push the changes to the remote trusted collection.",
+	Example: `  notary remove docker.io/library/alpine v1.0
+  notary remove docker.io/library/alpine v1.0 --roles targets/releases --publish`,
 }
 
 var cmdTUFInitTemplate = usageTemplate{
 	Use:   "init [ GUN ]",
 	Short: "Initializes a local trusted collection.",
 	Long:  "Initializes a local trusted collection identified by the Globally Unique Name. This is an online operation.",
+	Example: `  notary init docker.io/library/alpine
+  notary init docker.io/library/alpine --rootkey root.key --publish`,
 }
 
 var cmdTUFLookupTemplate = usageTemplate{
-	Use:   "lookup [ GUN ] <target>",
-	Short: "Looks up a specific target in a remote trusted collection.",
-	Long:  "Looks up a specific target in a remote trusted collection identified by the Globally Unique Name.",
+	Use:     "lookup [ GUN ] <target>",
+	Short:   "Looks up a specific target in a remote trusted collection.",
+	Long:    "Looks up a specific target in a remote trusted collection identified by the Globally Unique Name.",
+	Example: `  notary lookup docker.io/library/alpine v1.0`,
 }
 
 var cmdTUFPublishTemplate = usageTemplate{
-	Use:   "publish [ GUN ]",
-	Short: "Publishes the local trusted collection.",
-	Long:  "Publishes the local trusted collection identified by the Globally Unique Name, sending the local changes to a remote trusted server.",
+	Use:     "publish [ GUN ]",
+	Short:   "Publishes the local trusted collection.",
+	Long:    "Publishes the local trusted collection identified by the Globally Unique Name, sending the local changes to a remote trusted server.",
+	Example: `  notary publish docker.io/library/alpine`,
 }
 
 var cmdTUFStatusTemplate = usageTemplate{
 	Use:   "status [ GUN ]",
 	Short: "Displays status of unpublished changes to the local trusted collection.",
 	Long:  "Displays status of unpublished changes to the local trusted collection identified by the Globally Unique Name.",
+	Example: `  notary status docker.io/library/alpine
+  notary status docker.io/library/alpine --unstage 0,2
+  notary status docker.io/library/alpine --reset`,
 }
 
 var cmdTUFVerifyTemplate = usageTemplate{
 	Use:   "verify [ GUN ] <target>",
 	Short: "Verifies if the content is included in the remote trusted collection",
 	Long:  "Verifies if the data passed in STDIN is included in the remote trusted collection identified by the Globally Unique Name.",
+	Example: `  cat alpine-1.0.tar.gz | notary verify docker.io/library/alpine v1.0 > verified.tar.gz
+  notary verify docker.io/library/alpine v1.0 -i alpine-1.0.tar.gz -q`,
 }
 
 var cmdWitnessTemplate = usageTemplate{
-	Use:   "witness [ GUN ] <role> ...",
-	Short: "Marks roles to be re-signed the next time they're published",
-	Long:  "Marks roles to be re-signed the next time they're published. Currently will always bump version and expiry for role. N.B. behaviour may change when thresholding is introduced.",
+	Use:     "witness [ GUN ] <role> ...",
+	Short:   "Marks roles to be re-signed the next time they're published",
+	Long:    "Marks roles to be re-signed the next time they're published. Currently will always bump version and expiry for role. N.B. behaviour may change when thresholding is introduced.",
+	Example: `  notary witness docker.io/library/alpine targets targets/releases`,
 }
 
 var cmdTUFDeleteTemplate = usageTemplate{
 	Use:   "delete [ GUN ]",
 	Short: "Deletes all content for a trusted collection",
 	Long:  "Deletes all local content for a trusted collection identified by the Globally Unique Name. Remote data can also be deleted with an additional flag.",
+	Example: `  notary delete docker.io/library/alpine
+  notary delete docker.io/library/alpine --remote`,
 }
 
 type tufCommander struct {
@@ -183,6 +204,9 @@ func (t *tufCommander) tufWitness(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 	roles := args[1:]
 
 	// no online operations are performed by add so the transport argument
@@ -245,6 +269,9 @@ func (t *tufCommander) tufAddByHash(cmd *cobra.Command, args []string) error {
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 	targetName := args[1]
 	targetSize := args[2]
 
@@ -301,6 +328,9 @@ func (t *tufCommander) tufAdd(cmd *cobra.Command, args []string) error {
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 	targetName := args[1]
 	targetPath := args[2]
 
@@ -342,6 +372,9 @@ func (t *tufCommander) tufDeleteGUN(cmd *cobra.Command, args []string) error {
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 
 	trustPin, err := getTrustPinning(config)
 	if err != nil {
@@ -380,6 +413,9 @@ func (t *tufCommander) tufInit(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 
 	rt, err := getTransport(config, gun, readWrite)
 	if err != nil {
@@ -415,8 +451,12 @@ func (t *tufCommander) tufInit(cmd *cobra.Command, args []string) error {
 
 	var rootKeyID string
 	if len(rootKeyList) < 1 {
+		algorithm, err := getRootKeyAlgorithm(config)
+		if err != nil {
+			return err
+		}
 		cmd.Println("No root keys found. Generating a new root key...")
-		rootPublicKey, err := nRepo.CryptoService.Create(data.CanonicalRootRole, "", data.ECDSAKey)
+		rootPublicKey, err := nRepo.CryptoService.Create(data.CanonicalRootRole, "", algorithm)
 		if err != nil {
 			return err
 		}
@@ -469,6 +509,9 @@ func (t *tufCommander) tufList(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 
 	rt, err := getTransport(config, gun, readOnly)
 	if err != nil {
@@ -508,6 +551,9 @@ func (t *tufCommander) tufLookup(cmd *cobra.Command, args []string) error {
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 	targetName := args[1]
 
 	rt, err := getTransport(config, gun, readOnly)
@@ -546,6 +592,9 @@ func (t *tufCommander) tufStatus(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 
 	trustPin, err := getTrustPinning(config)
 	if err != nil {
@@ -607,6 +656,9 @@ func (t *tufCommander) tufPublish(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 
 	cmd.Println("Pushing changes to", gun)
 
@@ -642,6 +694,9 @@ func (t *tufCommander) tufRemove(cmd *cobra.Command, args []string) error {
 	}
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 	targetName := args[1]
 
 	trustPin, err := getTrustPinning(config)
@@ -681,8 +736,12 @@ func (t *tufCommander) tufVerify(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	defer payload.Close()
 
 	gun := args[0]
+	if err := validateGUN(gun); err != nil {
+		return err
+	}
 	targetName := args[1]
 
 	rt, err := getTransport(config, gun, readOnly)
@@ -706,11 +765,48 @@ func (t *tufCommander) tufVerify(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("error retrieving target by name:%s, error:%v", targetName, err)
 	}
 
-	if err := data.CheckHashes(payload, targetName, target.Hashes); err != nil {
-		return fmt.Errorf("data not present in the trusted collection, %v", err)
+	// The payload is hashed as it is read so that it is never held in memory.
+	// It may only be output once it has been verified, so unless we are quiet
+	// it is also spooled to a temporary file.  When writing to --output, the
+	// temporary file is created next to it and renamed into place once the
+	// payload is verified, so that the payload is not copied again.
+	spool := ioutil.Discard
+	var tmp *os.File
+	if !t.quiet {
+		if t.output != "" {
+			tmp, err = createTempFile(filepath.Dir(t.output), "."+filepath.Base(t.output)+".tmp", 0644)
+		} else {
+			tmp, err = ioutil.TempFile("", "notary-verify-")
+		}
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		spool = tmp
 	}
 
-	return feedback(t, payload)
+	if err := data.CheckHashesReader(io.TeeReader(payload, spool), targetName, target.Hashes); err != nil {
+		switch err.(type) {
+		case data.ErrMismatchedChecksum, data.ErrMissingMeta:
+			return fmt.Errorf("data not present in the trusted collection, %v", err)
+		}
+		return fmt.Errorf("Error reading content: %v", err)
+	}
+
+	switch {
+	case t.quiet:
+		return nil
+	case t.output != "":
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), t.output)
+	}
+	if _, err := tmp.Seek(0, os.SEEK_SET); err != nil {
+		return err
+	}
+	return feedback(tmp)
 }
 
 type passwordStore struct {
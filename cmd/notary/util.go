@@ -2,8 +2,16 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/docker/notary"
+	"github.com/docker/notary/storage"
 )
 
 const (
@@ -12,43 +20,47 @@ const (
 )
 
 // getPayload is a helper function to get the content used to be verified
-// either from an existing file or STDIN.
-func getPayload(t *tufCommander) ([]byte, error) {
+// either from an existing file or STDIN.  The content is streamed rather than
+// read into memory, so the caller must close the returned reader.
+func getPayload(t *tufCommander) (io.ReadCloser, error) {
 
 	// Reads from the given file
 	if t.input != "" {
-		// Please note that ReadFile will cut off the size if it was over 1e9.
-		// Thus, if the size of the file exceeds 1GB, the over part will not be
-		// loaded into the buffer.
-		payload, err := ioutil.ReadFile(t.input)
-		if err != nil {
-			return nil, err
-		}
-		return payload, nil
+		return os.Open(t.input)
 	}
 
-	// Reads all of the data on STDIN
-	payload, err := ioutil.ReadAll(os.Stdin)
-	if err != nil {
-		return nil, fmt.Errorf("Error reading content from STDIN: %v", err)
-	}
-	return payload, nil
+	// Reads the data on STDIN, which the caller should not close
+	return ioutil.NopCloser(os.Stdin), nil
 }
 
-// feedback is a helper function to print the payload to a file or STDOUT or keep quiet
-// due to the value of flag "quiet" and "output".
-func feedback(t *tufCommander, payload []byte) error {
-	// We only get here when everything goes well, since the flag "quiet" was
-	// provided, we output nothing but just return.
-	if t.quiet {
-		return nil
-	}
+// feedback is a helper function to print the verified payload to STDOUT.
+func feedback(payload io.Reader) error {
+	_, err := io.Copy(os.Stdout, payload)
+	return err
+}
 
-	// Flag "quiet" was not "true", that's why we get here.
-	if t.output != "" {
-		return ioutil.WriteFile(t.output, payload, 0644)
+// createTempFile creates a new file in dir whose name begins with prefix, like
+// ioutil.TempFile, but with the given mode instead of 0600.  The mode is
+// passed to open, so the umask applies to it just as it would to a file that
+// was written directly.
+func createTempFile(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(os.Getpid())))
+	for i := 0; i < 10000; i++ {
+		name := filepath.Join(dir, prefix+strconv.Itoa(int(r.Int31())))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		return f, err
 	}
+	return nil, fmt.Errorf("unable to create a temporary file in %s", dir)
+}
 
-	os.Stdout.Write(payload)
-	return nil
+// writeOutputFile atomically writes the payload to the given file, creating
+// any parent directories, so that a reader never sees a partial file.
+func writeOutputFile(path string, payload []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), notary.PubCertPerms); err != nil {
+		return err
+	}
+	return storage.WriteFileAtomic(path, payload, 0644)
 }
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -27,7 +29,10 @@ func TestGetPayload(t *testing.T) {
 
 	payload, err := getPayload(commander)
 	require.NoError(t, err)
-	require.Equal(t, "Release date: June 10, 2016 - Director: Duncan Jones", string(payload))
+	defer payload.Close()
+	content, err := ioutil.ReadAll(payload)
+	require.NoError(t, err)
+	require.Equal(t, "Release date: June 10, 2016 - Director: Duncan Jones", string(content))
 }
 
 func TestFeedback(t *testing.T) {
@@ -35,20 +40,47 @@ func TestFeedback(t *testing.T) {
 	require.NoError(t, err)
 	defer os.RemoveAll(tempDir)
 
-	file, err := os.Create(filepath.Join(tempDir, "content.txt"))
+	stdout, err := os.Create(filepath.Join(tempDir, "stdout"))
 	require.NoError(t, err)
+	defer stdout.Close()
 
-	// Expect it to print nothing since "quiet" takes priority.
-	commander := &tufCommander{
-		output: file.Name(),
-		quiet:  true,
-	}
+	oldStdout := os.Stdout
+	os.Stdout = stdout
+	defer func() { os.Stdout = oldStdout }()
+
+	payload := bytes.NewBufferString("Release date: June 10, 2016 - Director: Duncan Jones")
+	err = feedback(payload)
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(stdout.Name())
+	require.NoError(t, err)
+	require.Equal(t, "Release date: June 10, 2016 - Director: Duncan Jones", string(content))
+}
+
+func TestCreateTempFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "test-create-temp-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
 
-	payload := []byte("Release date: June 10, 2016 - Director: Duncan Jones")
-	err = feedback(commander, payload)
+	first, err := createTempFile(tempDir, ".content.tmp", 0666)
 	require.NoError(t, err)
+	defer first.Close()
+	second, err := createTempFile(tempDir, ".content.tmp", 0666)
+	require.NoError(t, err)
+	defer second.Close()
+
+	require.NotEqual(t, first.Name(), second.Name())
+	for _, f := range []*os.File{first, second} {
+		require.Equal(t, tempDir, filepath.Dir(f.Name()))
+		require.True(t, strings.HasPrefix(filepath.Base(f.Name()), ".content.tmp"))
+	}
 
-	content, err := ioutil.ReadFile(file.Name())
+	// the umask applies, so the mode matches that of a file written directly
+	direct := filepath.Join(tempDir, "direct")
+	require.NoError(t, ioutil.WriteFile(direct, nil, 0666))
+	directInfo, err := os.Stat(direct)
+	require.NoError(t, err)
+	tempInfo, err := first.Stat()
 	require.NoError(t, err)
-	require.Equal(t, "", string(content))
+	require.Equal(t, directInfo.Mode(), tempInfo.Mode())
 }
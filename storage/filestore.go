@@ -218,11 +218,40 @@ func (f *FilesystemStore) Set(name string, meta []byte) error {
 		return err
 	}
 
-	// if something already exists, just delete it and re-write it
-	os.RemoveAll(fp)
+	// if a directory already exists at this location, delete it, since it
+	// cannot be replaced by a rename.  Files are replaced atomically below.
+	if fi, err := os.Lstat(fp); err == nil && fi.IsDir() {
+		os.RemoveAll(fp)
+	}
+
+	return WriteFileAtomic(fp, meta, f.perms)
+}
+
+// WriteFileAtomic writes the data to a temporary file in the same directory
+// as the destination, and then renames it into place, so that readers never
+// see a partially written file.  On error, the temporary file is removed.
+func WriteFileAtomic(fp string, data []byte, perms os.FileMode) error {
+	// the temporary file name must not match the store's extension, or it
+	// could be picked up by ListFiles
+	tmp, err := ioutil.TempFile(filepath.Dir(fp), "."+filepath.Base(fp)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
 
-	// Write the file to disk
-	if err = ioutil.WriteFile(fp, meta, f.perms); err != nil {
+	if err = tmp.Chmod(perms); err == nil {
+		if _, err = tmp.Write(data); err == nil {
+			err = tmp.Sync()
+		}
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, fp)
+	}
+	if err != nil {
+		os.Remove(tmpName)
 		return err
 	}
 	return nil
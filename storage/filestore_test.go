@@ -75,6 +75,49 @@ func TestSetRemovesExistingFileBeforeWriting(t *testing.T) {
 	require.Equal(t, testContent, content, "Content written to file was corrupted.")
 }
 
+// overwriting an existing file replaces its contents and leaves no temporary
+// files behind in the store directory
+func TestSetOverwritesAtomically(t *testing.T) {
+	s, err := NewFilesystemStore(testDir, "metadata", "json")
+	require.Nil(t, err, "Initializing FilesystemStore returned unexpected error: %v", err)
+	defer os.RemoveAll(testDir)
+
+	require.NoError(t, s.Set("root", []byte("old data")))
+	require.NoError(t, s.Set("root", []byte("new data")))
+
+	content, err := ioutil.ReadFile(path.Join(testDir, "metadata", "root.json"))
+	require.NoError(t, err, "Error reading file: %v", err)
+	require.Equal(t, []byte("new data"), content, "Content written to file was corrupted.")
+
+	fileInfos, err := ioutil.ReadDir(path.Join(testDir, "metadata"))
+	require.NoError(t, err)
+	require.Len(t, fileInfos, 1)
+	require.Equal(t, "root.json", fileInfos[0].Name())
+	require.Equal(t, []string{"root"}, s.ListFiles())
+}
+
+// if the temporary file cannot be renamed into place, WriteFileAtomic returns
+// the error and removes the temporary file
+func TestWriteFileAtomicRemovesTempFileOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notary-write-atomic-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// a file cannot be renamed over a non-empty directory
+	dest := filepath.Join(dir, "root.json")
+	require.NoError(t, os.MkdirAll(filepath.Join(dest, "child"), 0700))
+
+	require.Error(t, WriteFileAtomic(dest, []byte("data"), 0600))
+
+	leftovers, err := filepath.Glob(filepath.Join(dir, ".root.json.tmp*"))
+	require.NoError(t, err)
+	require.Empty(t, leftovers)
+	fileInfos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, fileInfos, 1)
+	require.Equal(t, "root.json", fileInfos[0].Name())
+}
+
 func TestGetSized(t *testing.T) {
 	s, err := NewFilesystemStore(testDir, "metadata", "json")
 	require.Nil(t, err, "Initializing FilesystemStore returned unexpected error: %v", err)
@@ -24,7 +24,9 @@ type KeyInfo struct {
 }
 
 // GenericKeyStore is a wrapper for Storage instances that provides
-// translation between the []byte form and Public/PrivateKey objects
+// translation between the []byte form and Public/PrivateKey objects.
+// All of its methods are safe for concurrent use; accesses to the underlying
+// Storage are serialized, so the Storage itself need not be.
 type GenericKeyStore struct {
 	store Storage
 	sync.Mutex
@@ -86,6 +88,8 @@ func (s *GenericKeyStore) loadKeyInfo() {
 
 // GetKeyInfo returns the corresponding gun and role key info for a keyID
 func (s *GenericKeyStore) GetKeyInfo(keyID string) (KeyInfo, error) {
+	s.Lock()
+	defer s.Unlock()
 	if info, ok := s.keyInfoMap[keyID]; ok {
 		return info, nil
 	}
@@ -169,6 +173,8 @@ func (s *GenericKeyStore) GetKey(keyID string) (data.PrivateKey, string, error)
 
 // ListKeys returns a list of unique PublicKeys present on the KeyFileStore, by returning a copy of the keyInfoMap
 func (s *GenericKeyStore) ListKeys() map[string]KeyInfo {
+	s.Lock()
+	defer s.Unlock()
 	return copyKeyInfoMap(s.keyInfoMap)
 }
 
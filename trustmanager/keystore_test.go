@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/docker/notary"
@@ -453,6 +454,74 @@ func TestAddGetKeyInfoMemStore(t *testing.T) {
 	require.Equal(t, "", delgInfo.Gun)
 }
 
+// The key store can be used from many goroutines at once - run with -race
+func TestKeyStoreConcurrentAccess(t *testing.T) {
+	store := NewKeyMemoryStore(passphraseRetriever)
+
+	var (
+		readers sync.WaitGroup
+		workers = 5
+		errs    = make(chan error, workers)
+		done    = make(chan struct{})
+	)
+
+	// readers only ever list keys and look up key info
+	for i := 0; i < workers; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for keyID := range store.ListKeys() {
+					store.GetKeyInfo(keyID)
+				}
+			}
+		}()
+	}
+
+	// writers add, retrieve and remove their own keys
+	var writers sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		writers.Add(1)
+		go func(gun string) {
+			defer writers.Done()
+			privKey, err := utils.GenerateECDSAKey(rand.Reader)
+			if err != nil {
+				errs <- err
+				return
+			}
+			keyID := privKey.ID()
+			for j := 0; j < 10; j++ {
+				if err := store.AddKey(KeyInfo{Role: data.CanonicalTargetsRole, Gun: gun}, privKey); err != nil {
+					errs <- err
+					return
+				}
+				if _, _, err := store.GetKey(keyID); err != nil {
+					errs <- err
+					return
+				}
+				if err := store.RemoveKey(keyID); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(fmt.Sprintf("docker.com/notary%d", i))
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, store.ListKeys(), 0)
+}
+
 func TestGetDecryptedWithTamperedCipherText(t *testing.T) {
 	testExt := "key"
 	testAlias := data.CanonicalRootRole
@@ -190,6 +190,53 @@ func TestValidateRootWithPinnedCert(t *testing.T) {
 	require.Equal(t, typedSignedRoot, validatedSignedRoot)
 }
 
+// Cert ID pinning also accepts the ID of the leaf certificate's public key, so
+// that reissuing a certificate for the same key does not break the pin
+func TestTrustPinCertsCheckByPublicKeyID(t *testing.T) {
+	gun := "docker.com/notary"
+	memStore := trustmanager.NewKeyMemoryStore(passphraseRetriever)
+	cs := cryptoservice.NewCryptoService(memStore)
+
+	pubKey, err := cs.Create(data.CanonicalRootRole, gun, data.ECDSAKey)
+	require.NoError(t, err)
+	privKey, _, err := memStore.GetKey(pubKey.ID())
+	require.NoError(t, err)
+
+	cert, err := generateTestingCertificate(privKey, gun, notary.Year)
+	require.NoError(t, err)
+	reissuedCert, err := generateTestingCertificate(privKey, gun, 2*notary.Year)
+	require.NoError(t, err)
+
+	certKeyID := utils.CertToKey(cert).ID()
+	require.NotEqual(t, certKeyID, utils.CertToKey(reissuedCert).ID())
+	pubKeyID, err := utils.X509PublicKeyID(utils.CertToKey(cert))
+	require.NoError(t, err)
+	require.Equal(t, pubKey.ID(), pubKeyID)
+
+	// pinning the cert ID only matches that exact certificate
+	checker, err := trustpinning.NewTrustPinChecker(
+		trustpinning.TrustPinConfig{Certs: map[string][]string{gun: {certKeyID}}}, gun)
+	require.NoError(t, err)
+	require.True(t, checker(cert, nil))
+	require.False(t, checker(reissuedCert, nil))
+
+	// pinning the public key ID matches any certificate for that key
+	checker, err = trustpinning.NewTrustPinChecker(
+		trustpinning.TrustPinConfig{Certs: map[string][]string{gun: {pubKeyID}}}, gun)
+	require.NoError(t, err)
+	require.True(t, checker(cert, nil))
+	require.True(t, checker(reissuedCert, nil))
+
+	// but not a certificate for a different key
+	otherPubKey, err := cs.Create(data.CanonicalRootRole, gun, data.ECDSAKey)
+	require.NoError(t, err)
+	otherPrivKey, _, err := memStore.GetKey(otherPubKey.ID())
+	require.NoError(t, err)
+	otherCert, err := generateTestingCertificate(otherPrivKey, gun, notary.Year)
+	require.NoError(t, err)
+	require.False(t, checker(otherCert, nil))
+}
+
 func TestValidateRootWithPinnerCertAndIntermediates(t *testing.T) {
 	now := time.Now()
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
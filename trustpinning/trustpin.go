@@ -77,7 +77,17 @@ func (t trustPinChecker) certsCheck(leafCert *x509.Certificate, intCerts []*x509
 		logrus.Debug("error creating cert bundle: ", err.Error())
 		return false
 	}
-	return utils.StrSliceContains(t.pinnedCertIDs, key.ID())
+	if utils.StrSliceContains(t.pinnedCertIDs, key.ID()) {
+		return true
+	}
+	// also accept the ID of the leaf cert's public key, which unlike the cert
+	// ID does not change when a certificate is reissued for the same key
+	pubKeyID, err := utils.X509PublicKeyID(utils.CertToKey(leafCert))
+	if err != nil {
+		logrus.Debug("error computing public key ID of leaf cert: ", err.Error())
+		return false
+	}
+	return utils.StrSliceContains(t.pinnedCertIDs, pubKeyID)
 }
 
 func (t trustPinChecker) caCheck(leafCert *x509.Certificate, intCerts []*x509.Certificate) bool {
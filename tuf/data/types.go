@@ -162,6 +162,32 @@ func CheckHashes(payload []byte, name string, hashes Hashes) error {
 	return nil
 }
 
+// CheckHashesReader verifies the data read from r against the given hashes, in
+// the same way as CheckHashes.  The data is hashed as it is read, so it is never
+// held in memory.  Errors reading from r are returned unchanged.
+func CheckHashesReader(r io.Reader, name string, hashes Hashes) error {
+	var algorithms []string
+	for _, alg := range []string{notary.SHA256, notary.SHA512} {
+		if _, ok := hashes[alg]; ok {
+			algorithms = append(algorithms, alg)
+		}
+	}
+	if len(algorithms) == 0 {
+		return ErrMissingMeta{Role: name}
+	}
+
+	meta, err := NewFileMeta(r, algorithms...)
+	if err != nil {
+		return err
+	}
+	for _, alg := range algorithms {
+		if subtle.ConstantTimeCompare(meta.Hashes[alg], hashes[alg]) == 0 {
+			return ErrMismatchedChecksum{alg: alg, name: name, expected: hex.EncodeToString(hashes[alg])}
+		}
+	}
+	return nil
+}
+
 // CompareMultiHashes verifies that the two Hashes passed in can represent the same data.
 // This means that both maps must have at least one key defined for which they map, and no conflicts.
 // Note that we check the intersection of map keys, which adds support for non-default hash algorithms in notary
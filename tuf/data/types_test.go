@@ -2,9 +2,14 @@ package data
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"runtime"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/docker/go/canonical/json"
 	"github.com/docker/notary"
@@ -141,6 +146,85 @@ func TestCheckHashes(t *testing.T) {
 		expected: "d13e2b60d74c2e6f4f449b5e536814edf9a4827f5a9f4f957fc92e77609b9c92"}, badChecksum)
 }
 
+func TestCheckHashesReader(t *testing.T) {
+	raw := []byte("Bumblebee")
+
+	// Expected to fail since only an unsupported hash algorithm is provided
+	unSupported := make(Hashes)
+	unSupported["Arthas"] = []byte("is past away.")
+	err := CheckHashesReader(bytes.NewReader(raw), "metaName1", unSupported)
+	require.Equal(t, ErrMissingMeta{Role: "metaName1"}, err)
+
+	hashes := make(Hashes)
+	hashes[notary.SHA256], err = hex.DecodeString("d13e2b60d74c2e6f4f449b5e536814edf9a4827f5a9f4f957fc92e77609b9c92")
+	require.NoError(t, err)
+	hashes[notary.SHA512], err = hex.DecodeString("f2330f50d0f3ee56cf0d7f66aad8205e0cb9972c323208ffaa914ef7b3c240ae4774b5bbd1db2ce226ee967cfa9058173a853944f9b44e2e08abca385e2b7ed4")
+	require.NoError(t, err)
+	require.NoError(t, CheckHashesReader(bytes.NewReader(raw), "meta", hashes))
+
+	// Expected to fail because of the failure of sha512
+	// even though the sha256 is OK.
+	hashes[notary.SHA512] = []byte("malicious data")
+	err = CheckHashesReader(bytes.NewReader(raw), "metaName2", hashes)
+	require.Equal(t, ErrMismatchedChecksum{alg: notary.SHA512, name: "metaName2",
+		expected: hex.EncodeToString([]byte("malicious data"))}, err)
+
+	// Errors reading the data are returned as is
+	err = CheckHashesReader(iotest.TimeoutReader(bytes.NewReader(raw)), "meta", hashes)
+	require.Equal(t, iotest.ErrTimeout, err)
+}
+
+// zeroReader is an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// Checking the hashes of a large payload with CheckHashesReader allocates
+// a small, constant amount of memory rather than holding the payload
+func TestCheckHashesReaderMemoryUse(t *testing.T) {
+	const size = 64 << 20
+	hashes := Hashes{notary.SHA256: make([]byte, sha256.Size)}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	err := CheckHashesReader(io.LimitReader(zeroReader{}, size), "meta", hashes)
+	runtime.ReadMemStats(&after)
+
+	require.IsType(t, ErrMismatchedChecksum{}, err)
+	require.True(t, after.TotalAlloc-before.TotalAlloc < 1<<20,
+		"allocated %d bytes to check the hashes of %d bytes", after.TotalAlloc-before.TotalAlloc, size)
+}
+
+// BenchmarkCheckHashes reads the whole payload into memory before checking its
+// hashes, which is what notary verify used to do.  Compare its B/op with
+// BenchmarkCheckHashesReader.
+func BenchmarkCheckHashes(b *testing.B) {
+	const size = 16 << 20
+	hashes := Hashes{notary.SHA256: make([]byte, sha256.Size)}
+	b.ReportAllocs()
+	b.SetBytes(size)
+	for i := 0; i < b.N; i++ {
+		payload, err := ioutil.ReadAll(io.LimitReader(zeroReader{}, size))
+		require.NoError(b, err)
+		CheckHashes(payload, "meta", hashes)
+	}
+}
+
+func BenchmarkCheckHashesReader(b *testing.B) {
+	const size = 16 << 20
+	hashes := Hashes{notary.SHA256: make([]byte, sha256.Size)}
+	b.ReportAllocs()
+	b.SetBytes(size)
+	for i := 0; i < b.N; i++ {
+		CheckHashesReader(io.LimitReader(zeroReader{}, size), "meta", hashes)
+	}
+}
+
 func TestCheckValidHashStructures(t *testing.T) {
 	var err error
 	hashes := make(Hashes)
@@ -25,6 +25,19 @@ type Importer interface {
 	Set(string, []byte) error
 }
 
+// ExportAllKeys exports all keys in the store, in a consistent order so that
+// exporting the same store twice produces identical output
+func ExportAllKeys(to io.Writer, s Exporter) error {
+	keys := s.ListFiles()
+	sort.Strings(keys) // ensure consistency. ListFiles has no order guarantee
+	for _, loc := range keys {
+		if err := ExportKeys(to, s, loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ExportKeysByGUN exports all keys filtered to a GUN
 func ExportKeysByGUN(to io.Writer, s Exporter, gun string) error {
 	keys := s.ListFiles()
@@ -53,6 +66,7 @@ func ExportKeysByID(to io.Writer, s Exporter, ids []string) error {
 		want[id] = struct{}{}
 	}
 	keys := s.ListFiles()
+	sort.Strings(keys) // ensure consistency. ListFiles has no order guarantee
 	for _, k := range keys {
 		id := filepath.Base(k)
 		if _, ok := want[id]; ok {
@@ -5,10 +5,12 @@ import (
 	"crypto/rand"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"github.com/docker/notary"
 	"github.com/docker/notary/tuf/data"
 	"github.com/docker/notary/tuf/utils"
 	"github.com/stretchr/testify/require"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -187,6 +189,46 @@ func TestExportKeysByID(t *testing.T) {
 	require.Len(t, rest, 0)
 }
 
+// Exporting the same store twice produces byte-identical output, with the keys
+// ordered by their location in the store
+func TestExportKeysIsDeterministic(t *testing.T) {
+	s := NewTestExportStore()
+	var paths []string
+	for i := 0; i < 10; i++ {
+		b := &pem.Block{Headers: map[string]string{"role": "targets", "gun": "ankh"}}
+		b.Bytes = make([]byte, 1000)
+		rand.Read(b.Bytes)
+		path := fmt.Sprintf("morpork/identifier%d", i)
+		s.data[path] = pem.EncodeToMemory(b)
+		paths = append(paths, path)
+	}
+
+	exports := map[string]func(io.Writer) error{
+		"all":   func(w io.Writer) error { return ExportAllKeys(w, s) },
+		"byGUN": func(w io.Writer) error { return ExportKeysByGUN(w, s, "ankh") },
+		"byID": func(w io.Writer) error {
+			return ExportKeysByID(w, s, []string{"identifier9", "identifier0", "identifier5"})
+		},
+	}
+	for name, export := range exports {
+		first := bytes.NewBuffer(nil)
+		require.NoError(t, export(first), name)
+		for i := 0; i < 5; i++ {
+			again := bytes.NewBuffer(nil)
+			require.NoError(t, export(again), name)
+			require.Equal(t, first.Bytes(), again.Bytes(), name)
+		}
+	}
+
+	out := bytes.NewBuffer(nil)
+	require.NoError(t, ExportAllKeys(out, s))
+	var exported []string
+	for block, rest := pem.Decode(out.Bytes()); block != nil; block, rest = pem.Decode(rest) {
+		exported = append(exported, block.Headers["path"])
+	}
+	require.Equal(t, paths, exported)
+}
+
 func TestExport2InOneFile(t *testing.T) {
 	s := NewTestExportStore()
 